@@ -0,0 +1,58 @@
+// Package automap keeps track of which map cells the player has seen
+package automap
+
+import "github.com/hvassaa/gaster/raycasting"
+
+type Explored struct {
+	// Cells is indexed like the map, and exported so it can be saved
+	Cells [][]bool
+}
+
+func New(mab [][]raycasting.WallType) *Explored {
+	cells := make([][]bool, len(mab))
+	for y := range cells {
+		cells[y] = make([]bool, len(mab[y]))
+	}
+	return &Explored{Cells: cells}
+}
+
+func (e *Explored) Mark(c raycasting.Cell) {
+	if c.Y < 0 || c.Y >= len(e.Cells) || c.X < 0 || c.X >= len(e.Cells[c.Y]) {
+		return
+	}
+	e.Cells[c.Y][c.X] = true
+}
+
+// MarkRays marks the cells each ray passes through from origin, and the wall it hits
+func (e *Explored) MarkRays(origin raycasting.Coordinate, rays []raycasting.Ray, blockSize float64) {
+	for _, ray := range rays {
+		// rays that did not hit anything are left empty
+		if ray.Wt == 0 {
+			continue
+		}
+		for _, c := range raycasting.CellsBetween(origin, ray.Coord, blockSize) {
+			e.Mark(c)
+		}
+		e.Mark(ray.Cell)
+	}
+}
+
+func (e *Explored) Seen(x, y int) bool {
+	if y < 0 || y >= len(e.Cells) || x < 0 || x >= len(e.Cells[y]) {
+		return false
+	}
+	return e.Cells[y][x]
+}
+
+// Count is the number of explored cells
+func (e *Explored) Count() int {
+	n := 0
+	for _, row := range e.Cells {
+		for _, seen := range row {
+			if seen {
+				n++
+			}
+		}
+	}
+	return n
+}
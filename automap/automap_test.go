@@ -0,0 +1,47 @@
+package automap
+
+import (
+	"testing"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+func TestMarkRays(t *testing.T) {
+	blockSize := 10.
+	// 1 1 1 1 1
+	// 1 0 0 0 1
+	// 1 0 1 0 1
+	// 1 1 1 1 1
+	m := make([][]raycasting.WallType, 4)
+	for i := range m {
+		m[i] = []raycasting.WallType{1, 1, 1, 1, 1}
+	}
+	m[1][1], m[1][2], m[1][3] = 0, 0, 0
+	m[2][1], m[2][3] = 0, 0
+
+	e := New(m)
+	origin := raycasting.Coordinate{X: 15, Y: 25}
+	ray, err := raycasting.CastRay(origin, raycasting.PI_THREE_HALF, blockSize, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.MarkRays(origin, []raycasting.Ray{*ray, {}}, blockSize)
+
+	t.Run("Passed cells are seen", func(t *testing.T) {
+		if !e.Seen(1, 2) || !e.Seen(1, 1) {
+			t.Fatalf("Cells the ray passed should be seen, got: %v", e.Cells)
+		}
+	})
+
+	t.Run("Hit wall is seen", func(t *testing.T) {
+		if !e.Seen(1, 0) {
+			t.Fatalf("Wall the ray hit should be seen, got: %v", e.Cells)
+		}
+	})
+
+	t.Run("Other cells are not seen", func(t *testing.T) {
+		if e.Count() != 3 {
+			t.Fatalf("Only 3 cells should be seen, got: %v", e.Cells)
+		}
+	})
+}
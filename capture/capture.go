@@ -0,0 +1,232 @@
+// Package capture saves screenshots, and records frames as numbered PNGs or an animated GIF.
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type Format int
+
+const (
+	PNG_SEQUENCE Format = iota
+	GIF
+)
+
+const TIMESTAMP_LAYOUT = "20060102-150405.000"
+
+const (
+	// MAX_FRAMES is how many frames a recording keeps by default, a minute at 60 FPS with the default frame skip
+	MAX_FRAMES = 1800
+	// MAX_GIF_FRAMES is the default for GIFs, whose frames are all kept in memory until the end
+	MAX_GIF_FRAMES = 300
+	// FRAME_BUFFER is how many frames wait for the encoder, before more are dropped
+	FRAME_BUFFER = 16
+)
+
+type Capturer struct {
+	Dir    string
+	Format Format
+	// FrameSkip records only every FrameSkip'th frame, 1 records all
+	FrameSkip int
+	// GIFScale shrinks GIF frames by this factor, as GIFs get large fast
+	GIFScale int
+	// GIFDelay is the delay between GIF frames in 100ths of a second
+	GIFDelay int
+	// MaxFrames is how many frames are saved before the recording is full, and more are dropped
+	MaxFrames int
+
+	recording bool
+	frameNo   int
+	saved     int
+	dropped   int
+	// where the current recording is saved
+	target string
+	// frames are encoded in the background, as encoding is slow
+	frames chan frame
+	done   chan error
+}
+
+// frame is a copy of a recorded frame, and the file it goes to if it is saved on its own
+type frame struct {
+	path string
+	img  *image.RGBA
+}
+
+func New(dir string, format Format) *Capturer {
+	maxFrames := MAX_FRAMES
+	if format == GIF {
+		maxFrames = MAX_GIF_FRAMES
+	}
+	return &Capturer{
+		Dir:       dir,
+		Format:    format,
+		FrameSkip: 2,
+		GIFScale:  2,
+		GIFDelay:  4,
+		MaxFrames: maxFrames,
+	}
+}
+
+func (c *Capturer) Recording() bool {
+	return c.recording
+}
+
+// Dropped is how many frames of the current or last recording were dropped, as the encoder was behind
+func (c *Capturer) Dropped() int {
+	return c.dropped
+}
+
+// Full is whether the recording has MaxFrames frames, so it should be stopped
+func (c *Capturer) Full() bool {
+	return c.recording && c.MaxFrames > 0 && c.saved >= c.MaxFrames
+}
+
+func timestamp() string {
+	return time.Now().Format(TIMESTAMP_LAYOUT)
+}
+
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Screenshot saves img as a timestamped PNG and returns its path
+func (c *Capturer) Screenshot(img image.Image) (string, error) {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(c.Dir, "screenshot-"+timestamp()+".png")
+	return path, writePNG(path, img)
+}
+
+// Toggle starts a recording, or stops and saves the current one.
+// It returns the directory or file the recording is saved to.
+func (c *Capturer) Toggle() (string, error) {
+	var err error
+	if c.recording {
+		err = c.stop()
+	} else {
+		err = c.start()
+	}
+	return c.target, err
+}
+
+func (c *Capturer) start() error {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+	c.frameNo = 0
+	c.saved = 0
+	c.dropped = 0
+	c.frames = make(chan frame, FRAME_BUFFER)
+	c.done = make(chan error, 1)
+	switch c.Format {
+	case PNG_SEQUENCE:
+		c.target = filepath.Join(c.Dir, "recording-"+timestamp())
+		if err := os.Mkdir(c.target, 0o755); err != nil {
+			return err
+		}
+		go writePNGs(c.frames, c.done)
+	case GIF:
+		c.target = filepath.Join(c.Dir, "recording-"+timestamp()+".gif")
+		go c.writeGIF(c.target, c.frames, c.done)
+	default:
+		return errors.New("unknown capture format")
+	}
+	c.recording = true
+	return nil
+}
+
+// stop waits for the frames to be encoded
+func (c *Capturer) stop() error {
+	c.recording = false
+	close(c.frames)
+	return <-c.done
+}
+
+// writePNGs saves each frame as it comes, and sends the first error to done
+func writePNGs(frames chan frame, done chan error) {
+	var firstErr error
+	for f := range frames {
+		if err := writePNG(f.path, f.img); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	done <- firstErr
+}
+
+// writeGIF reduces the frames to the GIF palette as they come, and saves them to path when there are no more
+func (c *Capturer) writeGIF(path string, frames chan frame, done chan error) {
+	anim := &gif.GIF{}
+	scale, delay := max(c.GIFScale, 1), c.GIFDelay
+	for f := range frames {
+		anim.Image = append(anim.Image, paletted(f.img, scale))
+		anim.Delay = append(anim.Delay, delay)
+	}
+	if len(anim.Image) == 0 {
+		done <- errors.New("no frames were recorded")
+		return
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		done <- err
+		return
+	}
+	if err := gif.EncodeAll(file, anim); err != nil {
+		file.Close()
+		done <- err
+		return
+	}
+	done <- file.Close()
+}
+
+// Frame adds img to the current recording, and does nothing if not recording
+// or the recording is full. img is kept until it is encoded, so it should not
+// be changed after. The frame is dropped if the encoder is behind, rather
+// than waiting for it.
+func (c *Capturer) Frame(img *image.RGBA) {
+	if !c.recording || c.Full() {
+		return
+	}
+	c.frameNo++
+	if c.FrameSkip > 1 && (c.frameNo-1)%c.FrameSkip != 0 {
+		return
+	}
+	name := fmt.Sprintf("frame-%05d.png", c.saved+1)
+	select {
+	case c.frames <- frame{filepath.Join(c.target, name), img}:
+		c.saved++
+	default:
+		c.dropped++
+	}
+}
+
+// paletted shrinks img by scale, and dithers it to a palette a GIF can use
+func paletted(img image.Image, scale int) *image.Paletted {
+	b := img.Bounds()
+	small := image.NewRGBA(image.Rect(0, 0, b.Dx()/scale, b.Dy()/scale))
+	for y := 0; y < small.Bounds().Dy(); y++ {
+		for x := 0; x < small.Bounds().Dx(); x++ {
+			small.Set(x, y, img.At(b.Min.X+x*scale, b.Min.Y+y*scale))
+		}
+	}
+	res := image.NewPaletted(small.Bounds(), palette.Plan9)
+	draw.FloydSteinberg.Draw(res, res.Bounds(), small, image.Point{})
+	return res
+}
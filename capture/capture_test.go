@@ -0,0 +1,128 @@
+package capture
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	for x := 0; x < 20; x++ {
+		img.Set(x, 5, color.RGBA{200, 0, 0, 255})
+	}
+	return img
+}
+
+func TestScreenshot(t *testing.T) {
+	c := New(t.TempDir(), PNG_SEQUENCE)
+	path, err := c.Screenshot(testImage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Screenshot should exist at %v: %v", path, err)
+	}
+}
+
+func TestRecordPNGSequence(t *testing.T) {
+	c := New(t.TempDir(), PNG_SEQUENCE)
+	c.FrameSkip = 2
+	if _, err := c.Toggle(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		c.Frame(testImage())
+	}
+	dir, err := c.Toggle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "frame-*.png"))
+	if len(files) != 3 {
+		t.Fatalf("Should have saved 3 of 5 frames, got: %v", files)
+	}
+}
+
+func TestMaxFrames(t *testing.T) {
+	c := New(t.TempDir(), PNG_SEQUENCE)
+	c.FrameSkip = 1
+	c.MaxFrames = 2
+	if _, err := c.Toggle(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if c.Full() {
+			t.Fatalf("Recording should not be full after %d frames", i)
+		}
+		c.Frame(testImage())
+	}
+	if !c.Full() {
+		t.Fatalf("Recording should be full after MaxFrames")
+	}
+	c.Frame(testImage())
+	dir, err := c.Toggle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "frame-*.png")); len(files) != 2 {
+		t.Fatalf("Frames of a full recording should be dropped, got: %v", files)
+	}
+}
+
+func TestDroppedFrames(t *testing.T) {
+	c := New(t.TempDir(), PNG_SEQUENCE)
+	c.FrameSkip = 1
+	if _, err := c.Toggle(); err != nil {
+		t.Fatal(err)
+	}
+	// sent faster than they are encoded, so some may be dropped
+	for i := 0; i < 100; i++ {
+		c.Frame(testImage())
+	}
+	dir, err := c.Toggle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "frame-*.png"))
+	if len(files)+c.Dropped() != 100 {
+		t.Fatalf("Every frame should be saved or dropped, got: %d saved and %d dropped", len(files), c.Dropped())
+	}
+	if last := filepath.Join(dir, fmt.Sprintf("frame-%05d.png", len(files))); files[len(files)-1] != last {
+		t.Fatalf("Saved frames should be numbered without gaps, got: %v", files[len(files)-1])
+	}
+}
+
+func TestRecordGIF(t *testing.T) {
+	c := New(t.TempDir(), GIF)
+	c.FrameSkip = 1
+	if _, err := c.Toggle(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		c.Frame(testImage())
+	}
+	path, err := c.Toggle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	anim, err := gif.DecodeAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 4 {
+		t.Fatalf("GIF should have 4 frames, got: %v", len(anim.Image))
+	}
+	if anim.Image[0].Bounds().Dx() != 10 {
+		t.Fatalf("GIF frames should be scaled down to 10 pixels wide, got: %v", anim.Image[0].Bounds())
+	}
+}
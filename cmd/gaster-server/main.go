@@ -0,0 +1,56 @@
+// Command gaster-server hosts a game without a window, for players to join with -join
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/hvassaa/gaster/maps"
+	"github.com/hvassaa/gaster/netplay"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+const (
+	WORLD_WIDTH      = 1200.
+	WORLD_HEIGHT     = 1200.
+	BLOCK_SIZE       = 40.
+	BLOCKS_X     int = WORLD_WIDTH / BLOCK_SIZE
+	BLOCKS_Y     int = WORLD_HEIGHT / BLOCK_SIZE
+	SPEED            = 10.
+)
+
+func main() {
+	addr := flag.String("addr", ":7777", "address to listen on")
+	mapFile := flag.String("map", "", "CSV map to play on, instead of the demo map")
+	flag.Parse()
+
+	mab := maps.Demo(BLOCKS_X, BLOCKS_Y)
+	if *mapFile != "" {
+		var err error
+		if mab, err = maps.Load(*mapFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// players join in the middle of the map, like in the game
+	spawn := raycasting.Coordinate{
+		X: float64(len(mab[0])) * BLOCK_SIZE / 2,
+		Y: float64(len(mab)) * BLOCK_SIZE / 2,
+	}
+	s, err := netplay.Listen(*addr, mab, BLOCK_SIZE, spawn, SPEED)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("listening on %v", s.Addr())
+
+	stop := make(chan struct{})
+	go func() {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		<-interrupt
+		close(stop)
+	}()
+	s.Run(stop)
+	s.Close()
+}
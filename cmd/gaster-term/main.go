@@ -0,0 +1,84 @@
+// Command gaster-term runs the engine in a terminal, for use over SSH or in containers without a display
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hvassaa/gaster/maps"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/rendering/terminal"
+	"golang.org/x/term"
+)
+
+const (
+	WORLD_WIDTH      = 1200.
+	WORLD_HEIGHT     = 1200.
+	BLOCK_SIZE       = 40.
+	BLOCKS_X     int = WORLD_WIDTH / BLOCK_SIZE
+	BLOCKS_Y     int = WORLD_HEIGHT / BLOCK_SIZE
+	FOV              = 60
+	FPS              = 30
+)
+
+func main() {
+	plain := flag.Bool("plain", false, "only use ASCII characters, without colors")
+	flag.Parse()
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		log.Fatal("stdin is not a terminal")
+	}
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer term.Restore(fd, oldState)
+	// hide the cursor and clear the screen, and undo it when done
+	fmt.Print("\x1b[?25l\x1b[2J")
+	defer fmt.Print("\x1b[0m\x1b[?25h\x1b[2J\x1b[H")
+
+	mab := maps.Demo(BLOCKS_X, BLOCKS_Y)
+	p := &player.Player{
+		Coord: &raycasting.Coordinate{
+			X: WORLD_WIDTH / 2.,
+			Y: WORLD_HEIGHT / 2.,
+		},
+		Speed: 10.,
+	}
+	r := terminal.New(os.Stdout, 80, 24, p, BLOCK_SIZE)
+	r.Color = !*plain
+
+	keys := make(chan terminal.Key, 64)
+	go terminal.ReadKeys(os.Stdin, keys)
+
+	ticker := time.NewTicker(time.Second / FPS)
+	defer ticker.Stop()
+	for range ticker.C {
+	drain:
+		for {
+			select {
+			case key, ok := <-keys:
+				if !ok || key == terminal.KEY_QUIT {
+					return
+				}
+				terminal.Drive(p, key, BLOCK_SIZE, mab)
+			default:
+				break drain
+			}
+		}
+
+		// follow the terminal size, keeping the last line for the status
+		if width, height, err := term.GetSize(fd); err == nil {
+			r.Width, r.Height = width, height-1
+		}
+		rays := raycasting.CastRays(*p.Coord, p.Angle, FOV*raycasting.DEG_TO_RAD, r.Width, BLOCK_SIZE, mab)
+		r.Render(rays)
+		fmt.Printf("\r\n\x1b[0m\x1b[2Kx=%.0f y=%.0f angle=%.0f  wasd/arrows move, q/e turn, r/f look, x quits",
+			p.Coord.X, p.Coord.Y, p.Angle/raycasting.DEG_TO_RAD)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/hvassaa/gaster/automap"
+	"github.com/hvassaa/gaster/console"
+	"github.com/hvassaa/gaster/maps"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/rendering"
+)
+
+// registerCommands adds the console commands that change the game
+func (g *Game) registerCommands(c *console.Console) {
+	c.Register(console.Command{
+		Name: "noclip",
+		Help: "walks through walls",
+		Run: func(args []string) (string, error) {
+			g.world.Noclip = !g.world.Noclip
+			return fmt.Sprintf("noclip %v", g.world.Noclip), nil
+		},
+	})
+	c.Register(console.Command{
+		Name:  "teleport",
+		Usage: "<x> <y>",
+		Help:  "moves the player to world coordinates",
+		Run: func(args []string) (string, error) {
+			xy, err := console.Floats(args, 2)
+			if err != nil {
+				return "", err
+			}
+			if xy[0] < 0 || xy[1] < 0 || xy[0] >= float64(len(g.world.Map[0]))*BLOCK_SIZE || xy[1] >= float64(len(g.world.Map))*BLOCK_SIZE {
+				return "", errors.New("outside the map")
+			}
+			g.world.Player.Coord.X, g.world.Player.Coord.Y = xy[0], xy[1]
+			return "", nil
+		},
+	})
+	c.Register(console.Command{
+		Name:  "set fov",
+		Usage: "<degrees>",
+		Help:  "sets the field of view",
+		Run: func(args []string) (string, error) {
+			fov, err := console.Floats(args, 1)
+			if err != nil {
+				return "", err
+			}
+			if fov[0] < 10 || fov[0] > 170 {
+				return "", errors.New("fov should be between 10 and 170")
+			}
+			g.settings.View.FOV = fov[0]
+			return "", nil
+		},
+	})
+	c.Register(console.Command{
+		Name:  "set speed",
+		Usage: "<units per tick>",
+		Help:  "sets how fast the player moves",
+		Run: func(args []string) (string, error) {
+			speed, err := console.Floats(args, 1)
+			if err != nil {
+				return "", err
+			}
+			// the collision check only looks half a block ahead
+			if speed[0] <= 0 || speed[0] > BLOCK_SIZE/2 {
+				return "", fmt.Errorf("speed should be above 0 and at most %v", BLOCK_SIZE/2)
+			}
+			g.world.Player.Speed = speed[0]
+			return "", nil
+		},
+	})
+	c.Register(console.Command{
+		Name:  "give",
+		Usage: "<health|ammo> [amount]",
+		Help:  "gives the player something",
+		Run: func(args []string) (string, error) {
+			if len(args) == 0 || len(args) > 2 {
+				return "", errors.New("expected an item and an optional amount")
+			}
+			amount := 100
+			if len(args) == 2 {
+				n, err := strconv.Atoi(args[1])
+				if err != nil {
+					return "", errors.New("not a whole number: " + args[1])
+				}
+				amount = n
+			}
+			switch args[0] {
+			case "health":
+				g.world.Player.Health = min(g.world.Player.Health+amount, g.world.Player.MaxHealth)
+				return fmt.Sprintf("health %d", g.world.Player.Health), nil
+			case "ammo":
+				for _, wp := range g.world.Arsenal.Weapons {
+					wp.Give(amount)
+				}
+				if wp := g.world.Arsenal.Weapon(); wp != nil {
+					return fmt.Sprintf("%s ammo %d", wp.Type.Name, wp.Ammo), nil
+				}
+				return "no weapons", nil
+			}
+			return "", errors.New("unknown item: " + args[0])
+		},
+	})
+	c.Register(console.Command{
+		Name:  "load map",
+		Usage: "<csv file>",
+		Help:  "replaces the map",
+		Run: func(args []string) (string, error) {
+			if len(args) != 1 {
+				return "", errors.New("expected a file")
+			}
+			// the server would not know about the map, and keep correcting the player
+			if g.client != nil {
+				return "", errors.New("cannot load a map while online")
+			}
+			m, err := maps.Load(args[0])
+			if err != nil {
+				return "", err
+			}
+			g.setMap(args[0], m)
+			return fmt.Sprintf("loaded %dx%d map", len(m[0]), len(m)), nil
+		},
+	})
+	c.Register(console.Command{
+		Name: "reload textures",
+		Help: "reads the wall textures and the sky again",
+		Run: func(args []string) (res string, err error) {
+			// loading textures panics on bad files, which should not end the game
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%v", r)
+				}
+			}()
+			g.sky = rendering.LoadSky(rendering.SKY)
+			// the 3D renderers load the wall textures when they are created
+			g.updateRenders = true
+			return "", nil
+		},
+	})
+	c.Register(console.Command{
+		Name:  "debug",
+		Usage: "<inspect|rays|hud>",
+		Help:  "toggles the ray inspector, ray coloring on the 2D map, or the HUD",
+		Run: func(args []string) (string, error) {
+			if len(args) != 1 {
+				return "", errors.New("expected an overlay")
+			}
+			switch args[0] {
+			case "inspect":
+				g.toggleInspect()
+			case "rays":
+				g.colorRays = !g.colorRays
+				g.updateRenders = true
+			case "hud":
+				g.hud.Visible = !g.hud.Visible
+			default:
+				return "", errors.New("unknown overlay: " + args[0])
+			}
+			return "", nil
+		},
+	})
+}
+
+// setMap replaces the map, and moves the player to an open cell if it ends up in a wall.
+// The players sharing the screen are moved to the first player.
+// id is replay.DEMO_MAP or the path the map was loaded from.
+func (g *Game) setMap(id string, m [][]raycasting.WallType) {
+	defer g.placeLocals()
+	g.mapID = id
+	g.world.Map = m
+	g.explored = automap.New(m)
+	g.world.Lights.SetMap(m)
+	g.spawnEnemies()
+
+	// outdoor cells only make sense for the map they were made for
+	if len(g.outdoor) != len(m) || len(g.outdoor[0]) != len(m[0]) {
+		g.outdoor = make([][]bool, len(m))
+		for y := range g.outdoor {
+			g.outdoor[y] = make([]bool, len(m[y]))
+		}
+	}
+
+	x := int(math.Floor(g.world.Player.Coord.X / BLOCK_SIZE))
+	y := int(math.Floor(g.world.Player.Coord.Y / BLOCK_SIZE))
+	if y < 0 || y >= len(m) || x < 0 || x >= len(m[y]) || m[y][x] != 0 {
+	search:
+		for y = range m {
+			for x = range m[y] {
+				if m[y][x] == 0 {
+					break search
+				}
+			}
+		}
+		g.world.Player.Coord.X = (float64(x) + 0.5) * BLOCK_SIZE
+		g.world.Player.Coord.Y = (float64(y) + 0.5) * BLOCK_SIZE
+	}
+	g.updateRenders = true
+}
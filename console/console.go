@@ -0,0 +1,190 @@
+// Package console is a developer console, which runs commands typed by the
+// player. Any package can register its own commands.
+package console
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MAX_OUTPUT is how many lines of output are kept
+const MAX_OUTPUT = 200
+
+type Command struct {
+	// Name can be several words, like "set fov". The longest name matching
+	// the start of a line is run, with the rest of the words as args.
+	Name  string
+	Usage string
+	Help  string
+	// Run returns text to print, or an error which is printed instead
+	Run func(args []string) (string, error)
+}
+
+type Console struct {
+	Open   bool
+	Input  string
+	Output []string
+	// History holds the lines entered, oldest first
+	History   []string
+	historyAt int
+	commands  map[string]Command
+}
+
+func New() *Console {
+	c := &Console{commands: map[string]Command{}}
+	c.Register(Command{
+		Name: "help",
+		Help: "lists the commands",
+		Run: func(args []string) (string, error) {
+			var lines []string
+			for _, name := range c.Names() {
+				cmd := c.commands[name]
+				lines = append(lines, strings.TrimSpace(name+" "+cmd.Usage)+" - "+cmd.Help)
+			}
+			return strings.Join(lines, "\n"), nil
+		},
+	})
+	return c
+}
+
+func (c *Console) Register(cmd Command) error {
+	name := strings.Join(strings.Fields(cmd.Name), " ")
+	if name == "" {
+		return errors.New("command has no name")
+	}
+	if _, ok := c.commands[name]; ok {
+		return errors.New("command already registered: " + name)
+	}
+	cmd.Name = name
+	c.commands[name] = cmd
+	return nil
+}
+
+// Names is the sorted names of all commands
+func (c *Console) Names() []string {
+	names := make([]string, 0, len(c.commands))
+	for name := range c.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup finds the command with the longest name matching the first words
+func (c *Console) lookup(words []string) (Command, []string, bool) {
+	for n := len(words); n > 0; n-- {
+		if cmd, ok := c.commands[strings.Join(words[:n], " ")]; ok {
+			return cmd, words[n:], true
+		}
+	}
+	return Command{}, nil, false
+}
+
+// Execute runs a line, adds it to the history and prints the result
+func (c *Console) Execute(line string) {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return
+	}
+	c.History = append(c.History, strings.Join(words, " "))
+	c.historyAt = len(c.History)
+	c.Print("> " + strings.Join(words, " "))
+
+	cmd, args, ok := c.lookup(words)
+	if !ok {
+		c.Print("unknown command: " + words[0])
+		return
+	}
+	out, err := cmd.Run(args)
+	if err != nil {
+		c.Print(fmt.Sprintf("%v: %v", cmd.Name, err))
+		if cmd.Usage != "" {
+			c.Print("usage: " + cmd.Name + " " + cmd.Usage)
+		}
+		return
+	}
+	if out != "" {
+		c.Print(out)
+	}
+}
+
+// Print adds text to the output, which can have several lines
+func (c *Console) Print(text string) {
+	c.Output = append(c.Output, strings.Split(text, "\n")...)
+	if len(c.Output) > MAX_OUTPUT {
+		c.Output = c.Output[len(c.Output)-MAX_OUTPUT:]
+	}
+}
+
+// Submit executes the input and clears it
+func (c *Console) Submit() {
+	c.Execute(c.Input)
+	c.Input = ""
+}
+
+// Complete extends the input to the longest common prefix of the command
+// names starting with it, and returns all those names
+func (c *Console) Complete() []string {
+	prefix := strings.TrimLeft(c.Input, " ")
+	var matches []string
+	for _, name := range c.Names() {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	common := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(matches) == 1 {
+		common += " "
+	}
+	if len(common) > len(prefix) {
+		c.Input = common
+	}
+	return matches
+}
+
+// Previous replaces the input with the line entered before the one shown
+func (c *Console) Previous() {
+	if c.historyAt > 0 {
+		c.historyAt--
+		c.Input = c.History[c.historyAt]
+	}
+}
+
+// Next replaces the input with the line entered after the one shown, or
+// clears it when going past the newest line
+func (c *Console) Next() {
+	if c.historyAt < len(c.History)-1 {
+		c.historyAt++
+		c.Input = c.History[c.historyAt]
+	} else {
+		c.historyAt = len(c.History)
+		c.Input = ""
+	}
+}
+
+// Floats parses args as exactly n numbers
+func Floats(args []string, n int) ([]float64, error) {
+	if len(args) != n {
+		return nil, fmt.Errorf("expected %d numbers, got %d arguments", n, len(args))
+	}
+	res := make([]float64, n)
+	for i, a := range args {
+		f, err := strconv.ParseFloat(a, 64)
+		if err != nil {
+			return nil, fmt.Errorf("not a number: %v", a)
+		}
+		res[i] = f
+	}
+	return res, nil
+}
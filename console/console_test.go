@@ -0,0 +1,100 @@
+package console
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExecute(t *testing.T) {
+	c := New()
+	var got []string
+	c.Register(Command{Name: "set", Run: func(args []string) (string, error) {
+		return "", errors.New("unknown setting")
+	}})
+	c.Register(Command{Name: "set  fov", Usage: "degrees", Run: func(args []string) (string, error) {
+		got = args
+		return "fov set", nil
+	}})
+
+	t.Run("Longest name is run", func(t *testing.T) {
+		c.Execute("set fov 90")
+		if len(got) != 1 || got[0] != "90" {
+			t.Fatalf("set fov should get the args after its name, got: %v", got)
+		}
+		if c.Output[len(c.Output)-1] != "fov set" {
+			t.Fatalf("Output should be printed, got: %v", c.Output)
+		}
+	})
+
+	t.Run("Errors are printed", func(t *testing.T) {
+		c.Execute("set speed 2")
+		if !strings.Contains(c.Output[len(c.Output)-1], "unknown setting") {
+			t.Fatalf("Error should be printed, got: %v", c.Output)
+		}
+	})
+
+	t.Run("Unknown commands are reported", func(t *testing.T) {
+		c.Execute("fly")
+		if c.Output[len(c.Output)-1] != "unknown command: fly" {
+			t.Fatalf("Unknown command should be reported, got: %v", c.Output)
+		}
+	})
+
+	t.Run("Names must be unique", func(t *testing.T) {
+		if err := c.Register(Command{Name: "set fov"}); err == nil {
+			t.Fatalf("Registering a name twice should fail")
+		}
+	})
+}
+
+func TestComplete(t *testing.T) {
+	c := New()
+	c.Register(Command{Name: "noclip"})
+	c.Register(Command{Name: "set fov"})
+	c.Register(Command{Name: "set speed"})
+
+	t.Run("Common prefix", func(t *testing.T) {
+		c.Input = "s"
+		matches := c.Complete()
+		if c.Input != "set " || len(matches) != 2 {
+			t.Fatalf("Input should be completed to the common prefix, got: %q %v", c.Input, matches)
+		}
+	})
+
+	t.Run("Single match", func(t *testing.T) {
+		c.Input = "set f"
+		c.Complete()
+		if c.Input != "set fov " {
+			t.Fatalf("Input should be completed to the whole name, got: %q", c.Input)
+		}
+	})
+
+	t.Run("No match", func(t *testing.T) {
+		c.Input = "x"
+		if matches := c.Complete(); matches != nil || c.Input != "x" {
+			t.Fatalf("Input should be kept, got: %q %v", c.Input, matches)
+		}
+	})
+}
+
+func TestHistory(t *testing.T) {
+	c := New()
+	c.Execute("first")
+	c.Execute("second")
+
+	c.Previous()
+	c.Previous()
+	if c.Input != "first" {
+		t.Fatalf("Previous twice should give the first line, got: %q", c.Input)
+	}
+	c.Previous()
+	if c.Input != "first" {
+		t.Fatalf("Previous should stop at the first line, got: %q", c.Input)
+	}
+	c.Next()
+	c.Next()
+	if c.Input != "" {
+		t.Fatalf("Next past the newest line should clear the input, got: %q", c.Input)
+	}
+}
@@ -0,0 +1,100 @@
+// Package view draws a console as a drop down over the screen, and types into it
+package view
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hvassaa/gaster/console"
+	"github.com/hvassaa/gaster/hud"
+	"golang.org/x/image/font"
+)
+
+// TOGGLE_KEY opens and closes the console
+const TOGGLE_KEY = ebiten.KeyBackquote
+
+type View struct {
+	Console *console.Console
+	Face    font.Face
+	// Height is the part of the screen covered when open
+	Height     float64
+	Background color.Color
+	TextColor  color.Color
+}
+
+func New(c *console.Console, face font.Face) *View {
+	return &View{
+		Console:    c,
+		Face:       face,
+		Height:     0.5,
+		Background: color.RGBA{0, 0, 0, 210},
+		TextColor:  color.RGBA{220, 220, 220, 255},
+	}
+}
+
+// Update toggles the console and handles typing. It reports whether the
+// console is open, in which case the game should ignore the keyboard.
+func (v *View) Update() bool {
+	c := v.Console
+	if inpututil.IsKeyJustPressed(TOGGLE_KEY) {
+		c.Open = !c.Open
+		return true
+	}
+	if !c.Open {
+		return false
+	}
+
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if r != '`' && r != '~' {
+			c.Input += string(r)
+		}
+	}
+	switch {
+	case repeating(ebiten.KeyBackspace) && len(c.Input) > 0:
+		runes := []rune(c.Input)
+		c.Input = string(runes[:len(runes)-1])
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		c.Submit()
+	case inpututil.IsKeyJustPressed(ebiten.KeyTab):
+		if matches := c.Complete(); len(matches) > 1 {
+			c.Print(strings.Join(matches, "  "))
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp):
+		c.Previous()
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown):
+		c.Next()
+	}
+	return true
+}
+
+// repeating is true when key is pressed, and then repeatedly while held
+func repeating(key ebiten.Key) bool {
+	d := inpututil.KeyPressDuration(key)
+	return d == 1 || (d > 30 && d%3 == 0)
+}
+
+func (v *View) Draw(screen *ebiten.Image) {
+	if !v.Console.Open {
+		return
+	}
+	bounds := screen.Bounds()
+	height := int(float64(bounds.Dy()) * v.Height)
+	vector.DrawFilledRect(screen, float32(bounds.Min.X), float32(bounds.Min.Y), float32(bounds.Dx()), float32(height), v.Background, false)
+
+	lineHeight := v.Face.Metrics().Height.Ceil()
+	x := bounds.Min.X + 8
+	y := bounds.Min.Y + height - lineHeight - 4
+	hud.DrawText(screen, v.Face, "> "+v.Console.Input+"_", x, y, v.TextColor)
+	// newest output right above the input, going up until the top
+	out := v.Console.Output
+	for i := len(out) - 1; i >= 0; i-- {
+		y -= lineHeight
+		if y < bounds.Min.Y {
+			break
+		}
+		hud.DrawText(screen, v.Face, out[i], x, y, v.TextColor)
+	}
+}
@@ -0,0 +1,54 @@
+// Package display decides the resolution frames are rendered at, and how they are scaled to the window
+package display
+
+import "math"
+
+type Scaling string
+
+const (
+	// SCALING_INTEGER scales by whole numbers only, keeping pixels sharp
+	SCALING_INTEGER Scaling = "integer"
+	// SCALING_FILTERED scales to fill as much of the window as possible, smoothing pixels
+	SCALING_FILTERED Scaling = "filtered"
+)
+
+type Options struct {
+	// Width and Height are the logical resolution frames are rendered at,
+	// 0 renders at the window's resolution
+	Width, Height int
+	Scaling       Scaling
+	Fullscreen    bool
+	// Aspect is the width to height ratio kept with black bars when rendering
+	// at the window's resolution, 0 uses the window's ratio
+	Aspect float64
+}
+
+// RenderSize is the size frames are rendered at in a window of the given size
+func (o Options) RenderSize(windowWidth, windowHeight int) (int, int) {
+	if o.Width > 0 && o.Height > 0 {
+		return o.Width, o.Height
+	}
+	if o.Aspect > 0 {
+		w := min(float64(windowWidth), float64(windowHeight)*o.Aspect)
+		return max(int(w), 1), max(int(w/o.Aspect), 1)
+	}
+	return max(windowWidth, 1), max(windowHeight, 1)
+}
+
+// Placement is the scale and top left corner to draw a rendered frame at,
+// so it is centered in the window
+func (o Options) Placement(renderWidth, renderHeight, windowWidth, windowHeight int) (scale, x, y float64) {
+	scale = math.Min(float64(windowWidth)/float64(renderWidth), float64(windowHeight)/float64(renderHeight))
+	// frames larger than the window can only shrink, so they are not kept at whole numbers
+	if o.Scaling == SCALING_INTEGER && scale >= 1 {
+		scale = math.Floor(scale)
+	}
+	x = (float64(windowWidth) - float64(renderWidth)*scale) / 2
+	y = (float64(windowHeight) - float64(renderHeight)*scale) / 2
+	return scale, math.Floor(x), math.Floor(y)
+}
+
+// ToFrame translates a window position, like the cursor, to a position in the rendered frame
+func ToFrame(wx, wy int, scale, x, y float64) (int, int) {
+	return int((float64(wx) - x) / scale), int((float64(wy) - y) / scale)
+}
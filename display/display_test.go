@@ -0,0 +1,42 @@
+package display
+
+import "testing"
+
+func TestRenderSize(t *testing.T) {
+	t.Run("Follows the window", func(t *testing.T) {
+		w, h := Options{}.RenderSize(1600, 800)
+		if w != 1600 || h != 800 {
+			t.Fatalf("Should be 1600x800, got: %vx%v", w, h)
+		}
+	})
+
+	t.Run("Logical resolution", func(t *testing.T) {
+		w, h := Options{Width: 320, Height: 200}.RenderSize(1600, 800)
+		if w != 320 || h != 200 {
+			t.Fatalf("Should be 320x200, got: %vx%v", w, h)
+		}
+	})
+
+	t.Run("Keeps aspect ratio", func(t *testing.T) {
+		w, h := Options{Aspect: 4. / 3.}.RenderSize(1600, 900)
+		if w != 1200 || h != 900 {
+			t.Fatalf("Should be 1200x900, got: %vx%v", w, h)
+		}
+	})
+}
+
+func TestPlacement(t *testing.T) {
+	t.Run("Integer scaling", func(t *testing.T) {
+		scale, x, y := Options{Scaling: SCALING_INTEGER}.Placement(320, 200, 1000, 700)
+		if scale != 3 || x != 20 || y != 50 {
+			t.Fatalf("Should be scaled 3 times at (20, 50), got: %v at (%v, %v)", scale, x, y)
+		}
+	})
+
+	t.Run("Filtered scaling", func(t *testing.T) {
+		scale, x, y := Options{Scaling: SCALING_FILTERED}.Placement(320, 200, 1000, 700)
+		if scale != 3.125 || x != 0 || y != 37 {
+			t.Fatalf("Should be scaled 3.125 times at (0, 37), got: %v at (%v, %v)", scale, x, y)
+		}
+	})
+}
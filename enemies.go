@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hvassaa/gaster/console"
+	"github.com/hvassaa/gaster/enemy"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/projectile"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/rendering"
+	"github.com/hvassaa/gaster/replay"
+	"github.com/hvassaa/gaster/save"
+)
+
+const (
+	ENEMY_TYPES = "./resources/enemies/types.json"
+	DEMO_SPAWNS = "./resources/enemies/demo.json"
+	// CORPSE_SIZE is how tall dead enemies are drawn
+	CORPSE_SIZE = 0.15
+)
+
+// spawnsFile is where the enemies of a map are, next to a CSV map with
+// the same name ending in .enemies.json instead of .csv
+func spawnsFile(mapID string) string {
+	if mapID == replay.DEMO_MAP {
+		return DEMO_SPAWNS
+	}
+	return strings.TrimSuffix(mapID, ".csv") + ".enemies.json"
+}
+
+// spawnEnemies puts the enemies of the current map where they start, and
+// clears the projectiles flying. Maps without a spawns file, and maps of
+// online games, have no enemies.
+func (g *Game) spawnEnemies() {
+	group := enemy.NewGroup(g.world.Map, BLOCK_SIZE)
+	g.world.Enemies = group
+	g.world.Projectiles = projectile.NewGroup(g.world.Map, BLOCK_SIZE, g.projectileTypes)
+	if strings.HasPrefix(g.mapID, "net:") {
+		return
+	}
+	spawns, err := enemy.LoadSpawns(spawnsFile(g.mapID))
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		err = group.Spawn(g.enemyTypes, spawns)
+	}
+	if err != nil {
+		log.Printf("could not spawn enemies: %v", err)
+	}
+}
+
+// enemyEntities are the enemies, for saving
+func (g *Game) enemyEntities() []save.Entity {
+	var res []save.Entity
+	for _, e := range g.world.Enemies.Enemies {
+		res = append(res, save.Entity{
+			Kind:   e.Type.Name,
+			X:      e.Coord.X,
+			Y:      e.Coord.Y,
+			Angle:  e.Angle,
+			Health: e.Health,
+			State:  e.State.String(),
+		})
+	}
+	return res
+}
+
+// restoreEnemies puts saved enemies back. Enemies keep the patrol of their
+// spawn, if the saved ones match the spawns of the map.
+func (g *Game) restoreEnemies(entities []save.Entity) {
+	spawned := g.world.Enemies.Enemies
+	group := enemy.NewGroup(g.world.Map, BLOCK_SIZE)
+	for i, ent := range entities {
+		t, ok := g.enemyTypes[ent.Kind]
+		if !ok {
+			log.Printf("unknown enemy type %v in save", ent.Kind)
+			continue
+		}
+		e := enemy.New(t, raycasting.Coordinate{X: ent.X, Y: ent.Y})
+		e.Angle, e.Health = ent.Angle, ent.Health
+		// chasing and attacking need a target, which is found again
+		if state, err := enemy.ParseState(ent.State); err == nil && (state == enemy.DEAD || state == enemy.SEARCH) {
+			e.State = state
+		}
+		if len(spawned) == len(entities) && spawned[i].Type == t {
+			e.Patrol = spawned[i].Patrol
+		}
+		group.Add(e)
+	}
+	g.world.Enemies = group
+}
+
+// enemySprites are the enemies, with the dead ones lying on the floor
+func (g *Game) enemySprites() []rendering.Sprite {
+	var res []rendering.Sprite
+	for _, e := range g.world.Enemies.Enemies {
+		s := rendering.Sprite{Coord: e.Coord, Size: e.Type.Size, Color: e.Type.Color}
+		if e.State == enemy.DEAD {
+			s.Size = CORPSE_SIZE
+			s.Color = color.RGBA{e.Type.Color.R / 2, e.Type.Color.G / 2, e.Type.Color.B / 2, 255}
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// flashHits flashes the part of the screen of a player enemies or explosions hurt
+func (g *Game) flashHits() {
+	for _, hit := range g.world.Hits {
+		if i := g.playerIndex(hit.Player); hit.Damage > 0 && i >= 0 {
+			g.postfx.Flash(i, float64(hit.Damage)/20)
+		}
+	}
+	for _, ex := range g.world.Explosions {
+		for _, hit := range ex.Hits {
+			if p, ok := hit.Entity.(*player.Player); ok {
+				if i := g.playerIndex(p); i >= 0 {
+					g.postfx.Flash(i, float64(hit.Damage)/20)
+				}
+			}
+		}
+	}
+}
+
+func (g *Game) registerEnemyCommands(c *console.Console) {
+	c.Register(console.Command{
+		Name:  "spawn",
+		Usage: "<type>",
+		Help:  "puts an enemy two blocks in front of the player",
+		Run: func(args []string) (string, error) {
+			if len(args) != 1 {
+				return "", errors.New("expected an enemy type")
+			}
+			t, ok := g.enemyTypes[args[0]]
+			if !ok {
+				var names []string
+				for name := range g.enemyTypes {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				return "", fmt.Errorf("unknown enemy type, try one of %v", strings.Join(names, ", "))
+			}
+			p := g.world.Player
+			c := raycasting.Coordinate{
+				X: p.Coord.X + math.Cos(p.Angle)*2*BLOCK_SIZE,
+				Y: p.Coord.Y + math.Sin(p.Angle)*2*BLOCK_SIZE,
+			}
+			if !raycasting.HasLineOfSight(*p.Coord, c, BLOCK_SIZE, g.world.Map) {
+				return "", errors.New("there is a wall in the way")
+			}
+			e := enemy.New(t, c)
+			e.Angle = raycasting.NormalizeAngle(p.Angle + raycasting.PI)
+			g.world.Enemies.Add(e)
+			return "", nil
+		},
+	})
+}
@@ -0,0 +1,353 @@
+// Package enemy has enemies that patrol, notice players they can see, chase them
+// through the map and attack them. How each kind of enemy behaves is data.
+package enemy
+
+import (
+	"encoding/json"
+	"errors"
+	"image/color"
+	"math"
+	"os"
+
+	"github.com/hvassaa/gaster/pathfinding"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// SEARCH_TICKS is how long an enemy looks around where it lost the player, before patrolling again
+const SEARCH_TICKS = 120
+
+// Type is how a kind of enemy behaves. Distances are in blocks, so types work with any block size.
+type Type struct {
+	Name   string
+	Health int
+	// Speed is in blocks per tick, and TurnSpeed in degrees per tick
+	Speed, TurnSpeed float64
+	// SightRange is how far it sees, and FOV how wide, in degrees
+	SightRange, FOV float64
+	// ReactionTime is the ticks from seeing a player to chasing it
+	ReactionTime int
+	AttackRange  float64
+	AttackDamage int
+	// Projectile makes attacks ranged, launching a projectile of this type instead of doing AttackDamage
+	Projectile string `json:",omitempty"`
+	// AttackCooldown is the ticks between attacks
+	AttackCooldown int
+	// Radius is how wide it is, for being hit
+	Radius float64
+	// Size and Color are how it is drawn
+	Size  float64
+	Color color.RGBA
+}
+
+// LoadTypes reads a JSON list of types, by name
+func LoadTypes(path string) (map[string]*Type, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []*Type
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	types := map[string]*Type{}
+	for _, t := range list {
+		if t.Name == "" {
+			return nil, errors.New(path + ": type without a name")
+		}
+		if t.Health <= 0 || t.Speed <= 0 {
+			return nil, errors.New(path + ": " + t.Name + " should have health and speed")
+		}
+		types[t.Name] = t
+	}
+	return types, nil
+}
+
+type State int
+
+const (
+	PATROL State = iota
+	// ALERT is the reaction time after noticing a player
+	ALERT
+	CHASE
+	ATTACK
+	// SEARCH is looking around where a player was last seen
+	SEARCH
+	DEAD
+)
+
+var stateNames = []string{"patrol", "alert", "chase", "attack", "search", "dead"}
+
+func (s State) String() string {
+	if s < 0 || int(s) >= len(stateNames) {
+		return "unknown"
+	}
+	return stateNames[s]
+}
+
+// ParseState is the state named s
+func ParseState(s string) (State, error) {
+	for i, name := range stateNames {
+		if name == s {
+			return State(i), nil
+		}
+	}
+	return PATROL, errors.New("unknown enemy state " + s)
+}
+
+type Enemy struct {
+	Type   *Type
+	Coord  raycasting.Coordinate
+	Angle  float64
+	Health int
+	State  State
+	// Patrol are the cells walked between in turn while patrolling
+	Patrol []raycasting.Cell
+	// Target is the player being reacted to, chased or attacked, and LastSeen where it was last seen
+	Target   *player.Player
+	LastSeen raycasting.Coordinate
+	// timer counts down the reaction time or the search, and cooldown the time until it can attack
+	timer, cooldown int
+	patrolIdx       int
+	path            []raycasting.Cell
+	pathGoal        raycasting.Cell
+}
+
+func New(t *Type, c raycasting.Coordinate) *Enemy {
+	return &Enemy{Type: t, Coord: c, Health: t.Health}
+}
+
+// Hit is damage an enemy did to a player, or a projectile it fires at the player
+type Hit struct {
+	Enemy  *Enemy
+	Player *player.Player
+	Damage int
+	// Projectile is the type of projectile to launch for ranged attacks, which do no damage themselves
+	Projectile string
+}
+
+// Group is the enemies in a map
+type Group struct {
+	Enemies   []*Enemy
+	Map       [][]raycasting.WallType
+	BlockSize float64
+	// Paths finds the way for chasing and patrolling, so change cells of the map through it
+	Paths *pathfinding.Grid
+}
+
+func NewGroup(m [][]raycasting.WallType, blockSize float64) *Group {
+	return &Group{
+		Map:       m,
+		BlockSize: blockSize,
+		Paths:     pathfinding.NewGrid(m, pathfinding.Options{Diagonal: true}),
+	}
+}
+
+func (g *Group) Add(e *Enemy) {
+	g.Enemies = append(g.Enemies, e)
+}
+
+// Update moves every enemy a tick, and returns the damage done to the players
+func (g *Group) Update(players []*player.Player) []Hit {
+	var hits []Hit
+	for _, e := range g.Enemies {
+		if !g.update(e, players) {
+			continue
+		}
+		// ranged attacks are a projectile to launch, which does the damage if it hits
+		if e.Type.Projectile != "" {
+			hits = append(hits, Hit{Enemy: e, Player: e.Target, Projectile: e.Type.Projectile})
+			continue
+		}
+		e.Target.Health = max(e.Target.Health-e.Type.AttackDamage, 0)
+		hits = append(hits, Hit{Enemy: e, Player: e.Target, Damage: e.Type.AttackDamage})
+	}
+	return hits
+}
+
+// Alive is whether p can be noticed and attacked
+func Alive(p *player.Player) bool {
+	return p.Health > 0
+}
+
+// Sees is whether p is within the range and field of view of e, without walls in between
+func (g *Group) Sees(e *Enemy, p *player.Player) bool {
+	dist := e.Coord.DistanceTo(*p.Coord)
+	if dist > e.Type.SightRange*g.BlockSize {
+		return false
+	}
+	angle := math.Atan2(p.Coord.Y-e.Coord.Y, p.Coord.X-e.Coord.X)
+	if math.Abs(raycasting.RelativeAngle(angle, e.Angle)) > e.Type.FOV/2*raycasting.DEG_TO_RAD {
+		return false
+	}
+	return raycasting.HasLineOfSight(e.Coord, *p.Coord, g.BlockSize, g.Map)
+}
+
+// noticed is the nearest living player e sees, or nil
+func (g *Group) noticed(e *Enemy, players []*player.Player) *player.Player {
+	var res *player.Player
+	for _, p := range players {
+		if Alive(p) && g.Sees(e, p) && (res == nil || e.Coord.DistanceTo(*p.Coord) < e.Coord.DistanceTo(*res.Coord)) {
+			res = p
+		}
+	}
+	return res
+}
+
+// update runs the state machine of e for a tick, and reports whether it attacks its target
+func (g *Group) update(e *Enemy, players []*player.Player) bool {
+	if e.State == DEAD {
+		return false
+	}
+	e.cooldown = max(e.cooldown-1, 0)
+	// a dead target is forgotten, and so is a state without a target, as after loading a save
+	if (e.Target == nil || !Alive(e.Target)) && e.State != PATROL && e.State != SEARCH {
+		e.Target = nil
+		e.setState(PATROL, 0)
+	}
+
+	switch e.State {
+	case PATROL:
+		if p := g.noticed(e, players); p != nil {
+			e.Target, e.LastSeen = p, *p.Coord
+			e.setState(ALERT, e.Type.ReactionTime)
+			return false
+		}
+		g.patrol(e)
+	case ALERT:
+		e.turnTowards(e.LastSeen)
+		if e.timer--; e.timer > 0 {
+			return false
+		}
+		if g.Sees(e, e.Target) {
+			e.LastSeen = *e.Target.Coord
+			e.setState(CHASE, 0)
+		} else {
+			e.setState(PATROL, 0)
+		}
+	case CHASE:
+		if g.Sees(e, e.Target) {
+			e.LastSeen = *e.Target.Coord
+			if e.Coord.DistanceTo(e.LastSeen) <= e.Type.AttackRange*g.BlockSize {
+				e.setState(ATTACK, 0)
+				return false
+			}
+		}
+		if g.moveTo(e, e.LastSeen) {
+			e.setState(SEARCH, SEARCH_TICKS)
+		}
+	case ATTACK:
+		if !g.Sees(e, e.Target) || e.Coord.DistanceTo(*e.Target.Coord) > e.Type.AttackRange*g.BlockSize {
+			e.setState(CHASE, 0)
+			return false
+		}
+		e.LastSeen = *e.Target.Coord
+		e.turnTowards(e.LastSeen)
+		if e.cooldown == 0 {
+			e.cooldown = e.Type.AttackCooldown
+			return true
+		}
+	case SEARCH:
+		if p := g.noticed(e, players); p != nil {
+			e.Target, e.LastSeen = p, *p.Coord
+			e.setState(CHASE, 0)
+			return false
+		}
+		e.Angle = raycasting.NormalizeAngle(e.Angle + e.Type.TurnSpeed*raycasting.DEG_TO_RAD)
+		if e.timer--; e.timer <= 0 {
+			e.Target = nil
+			e.setState(PATROL, 0)
+		}
+	}
+	return false
+}
+
+// setState changes the state, with a timer for it, and forgets the path of the state before
+func (e *Enemy) setState(s State, timer int) {
+	e.State, e.timer = s, timer
+	e.path = nil
+}
+
+// Hurt damages e, which then knows where the attack came from and chases it
+func (e *Enemy) Hurt(damage int, from *player.Player) {
+	if e.State == DEAD {
+		return
+	}
+	e.Health -= damage
+	if e.Health <= 0 {
+		e.Health = 0
+		e.setState(DEAD, 0)
+		return
+	}
+	if from != nil && e.State != ATTACK {
+		e.Target, e.LastSeen = from, *from.Coord
+		e.setState(CHASE, 0)
+	}
+}
+
+// patrol walks e to the next patrol cell
+func (g *Group) patrol(e *Enemy) {
+	if len(e.Patrol) == 0 {
+		return
+	}
+	if g.moveTo(e, g.center(e.Patrol[e.patrolIdx])) {
+		e.patrolIdx = (e.patrolIdx + 1) % len(e.Patrol)
+	}
+}
+
+// moveTo moves e along a path towards c, and reports whether it is there
+func (g *Group) moveTo(e *Enemy, c raycasting.Coordinate) bool {
+	goal := g.cell(c)
+	if e.path == nil || goal != e.pathGoal {
+		e.path = g.Paths.Smooth(g.Paths.Path(g.cell(e.Coord), goal), g.BlockSize)
+		e.pathGoal = goal
+		if e.path == nil {
+			// nowhere to go, so it is as close as it gets
+			return true
+		}
+	}
+	// the corners of the path are walked through the centers of their cells, except the last one
+	next := c
+	for len(e.path) > 1 {
+		next = g.center(e.path[0])
+		if e.Coord.DistanceTo(next) > 1e-9 {
+			break
+		}
+		e.path = e.path[1:]
+	}
+	if len(e.path) == 1 {
+		next = c
+	}
+	e.turnTowards(next)
+	step := e.Type.Speed * g.BlockSize
+	dist := e.Coord.DistanceTo(next)
+	if dist <= step {
+		e.Coord = next
+		if len(e.path) == 1 {
+			e.path = nil
+			return true
+		}
+		return false
+	}
+	e.Coord.X += (next.X - e.Coord.X) / dist * step
+	e.Coord.Y += (next.Y - e.Coord.Y) / dist * step
+	return false
+}
+
+// turnTowards turns e to face c, at most TurnSpeed
+func (e *Enemy) turnTowards(c raycasting.Coordinate) {
+	if c == e.Coord {
+		return
+	}
+	diff := raycasting.RelativeAngle(math.Atan2(c.Y-e.Coord.Y, c.X-e.Coord.X), e.Angle)
+	limit := e.Type.TurnSpeed * raycasting.DEG_TO_RAD
+	e.Angle = raycasting.NormalizeAngle(e.Angle + max(min(diff, limit), -limit))
+}
+
+func (g *Group) cell(c raycasting.Coordinate) raycasting.Cell {
+	return raycasting.Cell{X: int(math.Floor(c.X / g.BlockSize)), Y: int(math.Floor(c.Y / g.BlockSize))}
+}
+
+func (g *Group) center(c raycasting.Cell) raycasting.Coordinate {
+	return raycasting.Coordinate{X: (float64(c.X) + 0.5) * g.BlockSize, Y: (float64(c.Y) + 0.5) * g.BlockSize}
+}
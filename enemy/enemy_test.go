@@ -0,0 +1,205 @@
+package enemy
+
+import (
+	"testing"
+
+	"github.com/hvassaa/gaster/maps"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+const blockSize = maps.ROOM_BLOCK_SIZE
+
+func testType() *Type {
+	return &Type{
+		Name: "test", Health: 10, Speed: 0.1, TurnSpeed: 180,
+		SightRange: 10, FOV: 90, ReactionTime: 5,
+		AttackRange: 1, AttackDamage: 3, AttackCooldown: 10,
+	}
+}
+
+// newGroup is the room with a wall, and an enemy in the top left looking east
+func newGroup() (*Group, *Enemy) {
+	g := NewGroup(maps.WithWall(), blockSize)
+	e := New(testType(), g.center(raycasting.Cell{X: 2, Y: 2}))
+	g.Add(e)
+	return g, e
+}
+
+func at(x, y float64) *player.Player {
+	return &player.Player{Coord: &raycasting.Coordinate{X: x * blockSize, Y: y * blockSize}, Health: 100, MaxHealth: 100}
+}
+
+func TestPerception(t *testing.T) {
+	g, e := newGroup()
+	t.Run("Sees players in front", func(t *testing.T) {
+		if !g.Sees(e, at(7.5, 2.5)) {
+			t.Fatalf("Enemy should see a player in front of it")
+		}
+	})
+	t.Run("Does not see behind", func(t *testing.T) {
+		if g.Sees(e, at(1.2, 2.5)) {
+			t.Fatalf("Enemy should not see a player behind it")
+		}
+	})
+	t.Run("Does not see through walls", func(t *testing.T) {
+		if g.Sees(e, at(12.5, 2.5)) {
+			t.Fatalf("Enemy should not see a player behind a wall")
+		}
+	})
+	t.Run("Does not see beyond its range", func(t *testing.T) {
+		e.Type = &Type{SightRange: 3, FOV: 90}
+		defer func() { e.Type = testType() }()
+		if g.Sees(e, at(7.5, 2.5)) {
+			t.Fatalf("Enemy should not see a player out of range")
+		}
+	})
+}
+
+func TestStates(t *testing.T) {
+	t.Run("Reacts, chases and attacks", func(t *testing.T) {
+		g, e := newGroup()
+		p := at(7.5, 2.5)
+		players := []*player.Player{p}
+		g.Update(players)
+		if e.State != ALERT || e.Target != p {
+			t.Fatalf("Enemy should be alerted by the player, got: %v", e.State)
+		}
+		for i := 0; i < e.Type.ReactionTime; i++ {
+			g.Update(players)
+		}
+		if e.State != CHASE {
+			t.Fatalf("Enemy should chase after its reaction time, got: %v", e.State)
+		}
+		var hits []Hit
+		for i := 0; i < 200 && len(hits) == 0; i++ {
+			hits = g.Update(players)
+		}
+		if e.State != ATTACK || len(hits) != 1 || p.Health != 97 {
+			t.Fatalf("Enemy should attack the player, got: %v with %d hits and health %d", e.State, len(hits), p.Health)
+		}
+		for i := 0; i < e.Type.AttackCooldown-1; i++ {
+			if hits := g.Update(players); len(hits) > 0 {
+				t.Fatalf("Enemy should wait for its cooldown before attacking again")
+			}
+		}
+		if hits := g.Update(players); len(hits) != 1 {
+			t.Fatalf("Enemy should attack again after its cooldown")
+		}
+	})
+
+	t.Run("Ranged attacks launch projectiles", func(t *testing.T) {
+		g, e := newGroup()
+		e.Type.Projectile, e.Type.AttackRange = "fireball", 6
+		p := at(6.5, 2.5)
+		var hits []Hit
+		for i := 0; i < 100 && len(hits) == 0; i++ {
+			hits = g.Update([]*player.Player{p})
+		}
+		if len(hits) != 1 || hits[0].Projectile != "fireball" || hits[0].Damage != 0 || p.Health != 100 {
+			t.Fatalf("Enemy should launch a fireball from afar, without hurting the player yet, got: %+v and health %d", hits, p.Health)
+		}
+	})
+
+	t.Run("Chases around walls", func(t *testing.T) {
+		g, e := newGroup()
+		p := at(7.5, 2.5)
+		e.Target, e.LastSeen = p, *p.Coord
+		e.setState(CHASE, 0)
+		// the player runs behind the wall, where it is lost from sight
+		p.Coord.X, p.Coord.Y = 12.5*blockSize, 2.5*blockSize
+		for i := 0; i < 1000 && e.State == CHASE; i++ {
+			g.Update([]*player.Player{p})
+		}
+		if e.State != SEARCH || g.cell(e.Coord) != (raycasting.Cell{X: 7, Y: 2}) {
+			t.Fatalf("Enemy should search where it last saw the player, got: %v at %v", e.State, g.cell(e.Coord))
+		}
+		// it is then seen again, and the enemy walks around the wall to it
+		e.LastSeen = *p.Coord
+		e.setState(CHASE, 0)
+		for i := 0; i < 2000 && e.State == CHASE; i++ {
+			g.Update([]*player.Player{p})
+		}
+		if e.State != ATTACK {
+			t.Fatalf("Enemy should reach the player around the wall, got: %v at %v", e.State, g.cell(e.Coord))
+		}
+		for _, c := range raycasting.CellsBetween(*p.Coord, e.Coord, blockSize) {
+			if g.Map[c.Y][c.X] != 0 {
+				t.Fatalf("Enemy should not be behind a wall, got: %v", e.Coord)
+			}
+		}
+	})
+
+	t.Run("Gives up searching", func(t *testing.T) {
+		g, e := newGroup()
+		e.setState(SEARCH, SEARCH_TICKS)
+		for i := 0; i < SEARCH_TICKS; i++ {
+			g.Update(nil)
+		}
+		if e.State != PATROL {
+			t.Fatalf("Enemy should patrol after searching, got: %v", e.State)
+		}
+	})
+
+	t.Run("Patrols", func(t *testing.T) {
+		g, e := newGroup()
+		e.Patrol = []raycasting.Cell{{X: 2, Y: 2}, {X: 2, Y: 8}}
+		seen := map[raycasting.Cell]bool{}
+		for i := 0; i < 500; i++ {
+			g.Update(nil)
+			seen[g.cell(e.Coord)] = true
+		}
+		if !seen[raycasting.Cell{X: 2, Y: 8}] || g.cell(e.Coord).X != 2 {
+			t.Fatalf("Enemy should walk between its patrol cells, got: %v", seen)
+		}
+	})
+
+	t.Run("Dies", func(t *testing.T) {
+		g, e := newGroup()
+		p := at(1.5, 1.5)
+		e.Hurt(4, p)
+		if e.State != CHASE || e.Target != p {
+			t.Fatalf("Enemy should chase who hurt it, got: %v", e.State)
+		}
+		e.Hurt(10, p)
+		g.Update([]*player.Player{p})
+		if e.State != DEAD || e.Health != 0 {
+			t.Fatalf("Enemy should be dead, got: %v with %d health", e.State, e.Health)
+		}
+	})
+
+	t.Run("States without a target are forgotten", func(t *testing.T) {
+		g, e := newGroup()
+		e.State = ATTACK
+		g.Update(nil)
+		if e.State != PATROL {
+			t.Fatalf("Enemy should patrol without a target, got: %v", e.State)
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	types, err := LoadTypes("../resources/enemies/types.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spawns, err := LoadSpawns("../resources/enemies/demo.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGroup(maps.Demo(30, 30), blockSize)
+	if err := g.Spawn(types, spawns); err != nil {
+		t.Fatalf("Demo spawns should use known types, got: %v", err)
+	}
+	for _, e := range g.Enemies {
+		c := g.cell(e.Coord)
+		if g.Map[c.Y][c.X] != 0 {
+			t.Fatalf("Enemies should spawn in open cells, got: %v", c)
+		}
+		for _, p := range e.Patrol {
+			if g.Paths.Path(c, p) == nil {
+				t.Fatalf("Patrol cell %v should be reachable from %v", p, c)
+			}
+		}
+	}
+}
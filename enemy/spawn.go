@@ -0,0 +1,46 @@
+package enemy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// Spawn is where an enemy of a type starts, with the cells it patrols between
+type Spawn struct {
+	Type string
+	Cell raycasting.Cell
+	// Angle is where it looks, in degrees
+	Angle  float64
+	Patrol []raycasting.Cell `json:",omitempty"`
+}
+
+// LoadSpawns reads a JSON list of spawns
+func LoadSpawns(path string) ([]Spawn, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spawns []Spawn
+	if err := json.Unmarshal(data, &spawns); err != nil {
+		return nil, err
+	}
+	return spawns, nil
+}
+
+// Spawn adds an enemy for every spawn, in the middle of its cell
+func (g *Group) Spawn(types map[string]*Type, spawns []Spawn) error {
+	for _, s := range spawns {
+		t, ok := types[s.Type]
+		if !ok {
+			return fmt.Errorf("unknown enemy type %v", s.Type)
+		}
+		e := New(t, g.center(s.Cell))
+		e.Angle = raycasting.NormalizeAngle(s.Angle * raycasting.DEG_TO_RAD)
+		e.Patrol = s.Patrol
+		g.Add(e)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Package hud draws widgets, like text and crosshairs, on top of the rendered view
+package hud
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+)
+
+type Anchor int
+
+const (
+	TOP_LEFT Anchor = iota
+	TOP
+	TOP_RIGHT
+	LEFT
+	CENTER
+	RIGHT
+	BOTTOM_LEFT
+	BOTTOM
+	BOTTOM_RIGHT
+)
+
+type Widget interface {
+	// Size is how much space the widget needs
+	Size(face font.Face) image.Point
+	// Draw draws the widget with its top left corner at (x, y)
+	Draw(dst *ebiten.Image, face font.Face, x, y int)
+}
+
+type placed struct {
+	widget Widget
+	anchor Anchor
+}
+
+type HUD struct {
+	Face font.Face
+	// Margin is the space in pixels between widgets, and between widgets and the screen edges
+	Margin  int
+	Visible bool
+	widgets []placed
+}
+
+func New(face font.Face) *HUD {
+	return &HUD{Face: face, Margin: 8, Visible: true}
+}
+
+// DefaultFace is a small bitmap font, which needs no font file
+func DefaultFace() font.Face {
+	return basicfont.Face7x13
+}
+
+// LoadFace loads a TTF or OTF font at size points
+func LoadFace(path string, size float64) (font.Face, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// Add places a widget at an anchor. Widgets at the same anchor are stacked
+// in the order they are added, away from the screen edge.
+func (h *HUD) Add(w Widget, anchor Anchor) {
+	h.widgets = append(h.widgets, placed{w, anchor})
+}
+
+func (h *HUD) Remove(w Widget) {
+	for i, p := range h.widgets {
+		if p.widget == w {
+			h.widgets = append(h.widgets[:i], h.widgets[i+1:]...)
+			return
+		}
+	}
+}
+
+func (h *HUD) Draw(screen *ebiten.Image) {
+	if !h.Visible {
+		return
+	}
+	bounds := screen.Bounds()
+	// how far down (or up for bottom anchors) the next widget at each anchor goes
+	offsets := map[Anchor]int{}
+	for _, p := range h.widgets {
+		size := p.widget.Size(h.Face)
+		var x, y int
+		switch p.anchor {
+		case TOP_LEFT, LEFT, BOTTOM_LEFT:
+			x = bounds.Min.X + h.Margin
+		case TOP, CENTER, BOTTOM:
+			x = bounds.Min.X + (bounds.Dx()-size.X)/2
+		default:
+			x = bounds.Max.X - h.Margin - size.X
+		}
+		switch p.anchor {
+		case TOP_LEFT, TOP, TOP_RIGHT:
+			y = bounds.Min.Y + h.Margin + offsets[p.anchor]
+		case LEFT, CENTER, RIGHT:
+			y = bounds.Min.Y + (bounds.Dy()-size.Y)/2 + offsets[p.anchor]
+		default:
+			y = bounds.Max.Y - h.Margin - size.Y - offsets[p.anchor]
+		}
+		offsets[p.anchor] += size.Y + h.Margin
+		p.widget.Draw(screen, h.Face, x, y)
+	}
+}
+
+// DrawText draws s with its top left corner at (x, y), with a shadow so it can be read on any background
+func DrawText(dst *ebiten.Image, face font.Face, s string, x, y int, clr color.Color) {
+	baseline := y + face.Metrics().Ascent.Ceil()
+	text.Draw(dst, s, face, x+1, baseline+1, color.RGBA{0, 0, 0, 200})
+	text.Draw(dst, s, face, x, baseline, clr)
+}
+
+// TextSize is the size of s drawn with face, s can have several lines
+func TextSize(face font.Face, s string) image.Point {
+	lines := strings.Split(s, "\n")
+	width := 0
+	for _, l := range lines {
+		width = max(width, font.MeasureString(face, l).Ceil())
+	}
+	return image.Pt(width, len(lines)*face.Metrics().Height.Ceil())
+}
@@ -0,0 +1,110 @@
+package hud
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+	"golang.org/x/image/font"
+)
+
+// Text shows the string returned by Value, which is called every frame
+type Text struct {
+	Value func() string
+	Color color.Color
+}
+
+func (t *Text) Size(face font.Face) image.Point {
+	return TextSize(face, t.Value())
+}
+
+func (t *Text) Draw(dst *ebiten.Image, face font.Face, x, y int) {
+	DrawText(dst, face, t.Value(), x, y, t.Color)
+}
+
+// FPS shows the frames and ticks per second
+func FPS() *Text {
+	return &Text{
+		Value: func() string {
+			return fmt.Sprintf("FPS %.0f  TPS %.0f", ebiten.ActualFPS(), ebiten.ActualTPS())
+		},
+		Color: color.RGBA{200, 200, 200, 255},
+	}
+}
+
+// PlayerInfo shows the coordinates and angles of p
+func PlayerInfo(p *player.Player) *Text {
+	return &Text{
+		Value: func() string {
+			return fmt.Sprintf("x %.0f  y %.0f  angle %.0f  pitch %.0f",
+				p.Coord.X, p.Coord.Y, p.Angle/raycasting.DEG_TO_RAD, p.HozAngle)
+		},
+		Color: color.RGBA{200, 200, 200, 255},
+	}
+}
+
+type Crosshair struct {
+	// Length is the length in pixels of each arm, and Gap the space between the arms and the center
+	Length, Gap float32
+	Color       color.Color
+}
+
+func (c *Crosshair) Size(face font.Face) image.Point {
+	side := int(2 * (c.Length + c.Gap))
+	return image.Pt(side, side)
+}
+
+func (c *Crosshair) Draw(dst *ebiten.Image, face font.Face, x, y int) {
+	cx := float32(x) + c.Length + c.Gap
+	cy := float32(y) + c.Length + c.Gap
+	vector.StrokeLine(dst, cx-c.Gap-c.Length, cy, cx-c.Gap, cy, 2, c.Color, false)
+	vector.StrokeLine(dst, cx+c.Gap, cy, cx+c.Gap+c.Length, cy, 2, c.Color, false)
+	vector.StrokeLine(dst, cx, cy-c.Gap-c.Length, cx, cy-c.Gap, 2, c.Color, false)
+	vector.StrokeLine(dst, cx, cy+c.Gap, cx, cy+c.Gap+c.Length, 2, c.Color, false)
+}
+
+// SLOT_BAR_WIDTH is the width in pixels of the bar drawn by a Slot with a Max
+const SLOT_BAR_WIDTH = 100
+
+// Slot shows a labelled value, like health or ammo. If Max returns more
+// than 0 a bar filled by Value/Max is drawn next to the number.
+type Slot struct {
+	Label      string
+	Value, Max func() int
+	Color      color.Color
+}
+
+func (s *Slot) text() string {
+	return fmt.Sprintf("%s %d", s.Label, s.Value())
+}
+
+func (s *Slot) hasBar() bool {
+	return s.Max != nil && s.Max() > 0
+}
+
+func (s *Slot) Size(face font.Face) image.Point {
+	size := TextSize(face, s.text())
+	if s.hasBar() {
+		size.X += SLOT_BAR_WIDTH + size.Y
+	}
+	return size
+}
+
+func (s *Slot) Draw(dst *ebiten.Image, face font.Face, x, y int) {
+	t := s.text()
+	DrawText(dst, face, t, x, y, s.Color)
+	if !s.hasBar() {
+		return
+	}
+	size := TextSize(face, t)
+	bx := float32(x + size.X + size.Y)
+	by := float32(y) + float32(size.Y)/4
+	bh := float32(size.Y) / 2
+	fill := max(min(float32(s.Value())/float32(s.Max()), 1), 0)
+	vector.DrawFilledRect(dst, bx, by, SLOT_BAR_WIDTH, bh, color.RGBA{0, 0, 0, 160}, false)
+	vector.DrawFilledRect(dst, bx, by, SLOT_BAR_WIDTH*fill, bh, s.Color, false)
+}
@@ -0,0 +1,213 @@
+// Package input turns physical inputs into named actions, so game logic
+// does not depend on which keys, buttons or sticks are used.
+package input
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// Button is a digital action, used as a bit in Frame.Buttons
+type Button uint32
+
+const (
+	USE Button = 1 << iota
+	PAUSE
+	MINIMAP
+	ZOOM_IN
+	ZOOM_OUT
+	FIRE
+	RELOAD
+	NEXT_WEAPON
+)
+
+// Action names bindings in the config. Each axis has an action per direction.
+type Action string
+
+const (
+	MOVE_FORWARD    Action = "MoveForward"
+	MOVE_BACKWARD   Action = "MoveBackward"
+	STRAFE_LEFT     Action = "StrafeLeft"
+	STRAFE_RIGHT    Action = "StrafeRight"
+	TURN_LEFT       Action = "TurnLeft"
+	TURN_RIGHT      Action = "TurnRight"
+	LOOK_UP         Action = "LookUp"
+	LOOK_DOWN       Action = "LookDown"
+	ACTION_USE      Action = "Use"
+	ACTION_PAUSE    Action = "Pause"
+	ACTION_MAP      Action = "Minimap"
+	ACTION_ZOOM_IN  Action = "ZoomIn"
+	ACTION_ZOOM_OUT Action = "ZoomOut"
+	ACTION_FIRE     Action = "Fire"
+	ACTION_RELOAD   Action = "Reload"
+	ACTION_NEXT     Action = "NextWeapon"
+)
+
+// ACTIONS lists every action, in the order they are shown to the player
+var ACTIONS = []Action{
+	MOVE_FORWARD, MOVE_BACKWARD, STRAFE_LEFT, STRAFE_RIGHT, TURN_LEFT, TURN_RIGHT,
+	LOOK_UP, LOOK_DOWN, ACTION_USE, ACTION_PAUSE, ACTION_MAP, ACTION_ZOOM_IN, ACTION_ZOOM_OUT,
+	ACTION_FIRE, ACTION_RELOAD, ACTION_NEXT,
+}
+
+// BUTTONS maps the actions that are buttons to their bit
+var BUTTONS = map[Action]Button{
+	ACTION_USE:      USE,
+	ACTION_PAUSE:    PAUSE,
+	ACTION_MAP:      MINIMAP,
+	ACTION_ZOOM_IN:  ZOOM_IN,
+	ACTION_ZOOM_OUT: ZOOM_OUT,
+	ACTION_FIRE:     FIRE,
+	ACTION_RELOAD:   RELOAD,
+	ACTION_NEXT:     NEXT_WEAPON,
+}
+
+// Frame is the actions of a single tick
+type Frame struct {
+	// Move is forward and Strafe is right, both between -1 and 1
+	Move   float64 `json:",omitempty"`
+	Strafe float64 `json:",omitempty"`
+	// Turn is how far to turn right in radians, and Look how far to look up,
+	// in the units of Player.IncreaseHozAngle
+	Turn float64 `json:",omitempty"`
+	Look float64 `json:",omitempty"`
+	// Buttons has the bits of the buttons held down
+	Buttons Button `json:",omitempty"`
+}
+
+func (f Frame) Pressed(b Button) bool {
+	return f.Buttons&b != 0
+}
+
+// JustPressed is true if b is held in f, but was not in the frame before
+func (f Frame) JustPressed(prev Frame, b Button) bool {
+	return f.Pressed(b) && !prev.Pressed(b)
+}
+
+// Axis binds a gamepad stick axis to an action in each direction
+type Axis struct {
+	// Axis is one of LeftX, LeftY, RightX and RightY
+	Axis string
+	// Negative and Positive are the actions for each direction of the stick
+	Negative, Positive Action
+}
+
+type Config struct {
+	// Keys and MouseButtons and GamepadButtons bind names of inputs to actions.
+	// Keys are named like ebiten.Key, mouse buttons Left, Right and Middle,
+	// and gamepad buttons as on an Xbox controller: A, B, X, Y, LB, RB, LT, RT,
+	// Back, Start, LS, RS, Up, Down, Left and Right.
+	Keys           map[Action][]string
+	MouseButtons   map[Action][]string
+	GamepadButtons map[Action][]string
+	GamepadAxes    []Axis
+	// Sensitivity scales how far the mouse turns the view
+	Sensitivity float64
+	// TurnSpeed is how many degrees a key, or a stick pushed all the way, turns per tick
+	TurnSpeed float64
+	// DeadZone is how far a stick must be pushed before it counts, between 0 and 1
+	DeadZone float64
+	InvertY  bool
+}
+
+func Default() Config {
+	return Config{
+		Keys: map[Action][]string{
+			MOVE_FORWARD:    {"W"},
+			MOVE_BACKWARD:   {"S"},
+			STRAFE_LEFT:     {"A"},
+			STRAFE_RIGHT:    {"D"},
+			TURN_LEFT:       {"Q", "ArrowLeft"},
+			TURN_RIGHT:      {"E", "ArrowRight"},
+			LOOK_UP:         {"ArrowUp"},
+			LOOK_DOWN:       {"ArrowDown"},
+			ACTION_USE:      {"F"},
+			ACTION_PAUSE:    {"Escape"},
+			ACTION_MAP:      {"M"},
+			ACTION_ZOOM_IN:  {"Equal"},
+			ACTION_ZOOM_OUT: {"Minus"},
+			ACTION_FIRE:     {"Space"},
+			ACTION_RELOAD:   {"R"},
+			ACTION_NEXT:     {"X"},
+		},
+		MouseButtons: map[Action][]string{
+			ACTION_USE:  {"Right"},
+			ACTION_FIRE: {"Left"},
+		},
+		GamepadButtons: map[Action][]string{
+			ACTION_USE:      {"A"},
+			ACTION_PAUSE:    {"Start"},
+			ACTION_MAP:      {"Back"},
+			ACTION_ZOOM_IN:  {"RB"},
+			ACTION_ZOOM_OUT: {"LB"},
+			ACTION_FIRE:     {"RT"},
+			ACTION_RELOAD:   {"X"},
+			ACTION_NEXT:     {"Y"},
+		},
+		GamepadAxes: []Axis{
+			{Axis: "LeftY", Negative: MOVE_FORWARD, Positive: MOVE_BACKWARD},
+			{Axis: "LeftX", Negative: STRAFE_LEFT, Positive: STRAFE_RIGHT},
+			{Axis: "RightX", Negative: TURN_LEFT, Positive: TURN_RIGHT},
+			{Axis: "RightY", Negative: LOOK_UP, Positive: LOOK_DOWN},
+		},
+		Sensitivity: 1,
+		TurnSpeed:   2.3,
+		DeadZone:    0.2,
+	}
+}
+
+// Load reads a config from a JSON file. Bindings missing from the file keep their defaults.
+func Load(path string) (Config, error) {
+	c := Default()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Default(), err
+	}
+	return c, nil
+}
+
+// ApplyDeadZone maps a stick value to 0 inside the dead zone, and scales
+// the rest so it still goes from 0 to 1
+func (c Config) ApplyDeadZone(v float64) float64 {
+	if c.DeadZone >= 1 {
+		return 0
+	}
+	if v > -c.DeadZone && v < c.DeadZone {
+		return 0
+	}
+	if v > 0 {
+		return (v - c.DeadZone) / (1 - c.DeadZone)
+	}
+	return (v + c.DeadZone) / (1 - c.DeadZone)
+}
+
+// Values accumulates how strongly each action is held, between 0 and 1,
+// and turns them into a Frame
+type Values map[Action]float64
+
+// Set keeps the strongest value given for an action
+func (v Values) Set(a Action, value float64) {
+	v[a] = max(v[a], min(value, 1))
+}
+
+// Frame combines opposite actions into axes. Mouse movement is added to
+// Turn and Look by the caller, since it is not limited to 1.
+func (v Values) Frame(c Config) Frame {
+	f := Frame{
+		Move:   v[MOVE_FORWARD] - v[MOVE_BACKWARD],
+		Strafe: v[STRAFE_RIGHT] - v[STRAFE_LEFT],
+		Turn:   (v[TURN_RIGHT] - v[TURN_LEFT]) * c.TurnSpeed * raycasting.DEG_TO_RAD,
+		Look:   (v[LOOK_UP] - v[LOOK_DOWN]) * c.TurnSpeed,
+	}
+	for a, b := range BUTTONS {
+		if v[a] > 0.5 {
+			f.Buttons |= b
+		}
+	}
+	return f
+}
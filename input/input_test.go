@@ -0,0 +1,66 @@
+package input
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyDeadZone(t *testing.T) {
+	c := Config{DeadZone: 0.2}
+	cases := map[float64]float64{0.1: 0, -0.19: 0, 0.2: 0, 0.6: 0.5, 1: 1, -1: -1, -0.6: -0.5}
+	for in, want := range cases {
+		if got := c.ApplyDeadZone(in); math.Abs(got-want) > 1e-9 {
+			t.Fatalf("Stick at %v should give %v, got: %v", in, want, got)
+		}
+	}
+}
+
+func TestValuesFrame(t *testing.T) {
+	c := Default()
+	v := Values{}
+	v.Set(MOVE_FORWARD, 1)
+	v.Set(MOVE_BACKWARD, 0.25)
+	v.Set(STRAFE_LEFT, 2)
+	v.Set(ACTION_USE, 1)
+	f := v.Frame(c)
+
+	t.Run("Opposite actions cancel", func(t *testing.T) {
+		if f.Move != 0.75 {
+			t.Fatalf("Move should be forward minus backward, got: %v", f.Move)
+		}
+	})
+
+	t.Run("Values are limited to 1", func(t *testing.T) {
+		if f.Strafe != -1 {
+			t.Fatalf("Strafe should be -1, got: %v", f.Strafe)
+		}
+	})
+
+	t.Run("Buttons", func(t *testing.T) {
+		if !f.Pressed(USE) || f.Pressed(PAUSE) {
+			t.Fatalf("Only use should be pressed, got: %b", f.Buttons)
+		}
+		if !f.JustPressed(Frame{}, USE) || f.JustPressed(f, USE) {
+			t.Fatalf("Use should only be just pressed after a frame without it")
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bindings.json")
+	if err := os.WriteFile(path, []byte(`{"Keys": {"MoveForward": ["ArrowUp"]}, "InvertY": true}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.InvertY || len(c.Keys[MOVE_FORWARD]) != 1 || c.Keys[MOVE_FORWARD][0] != "ArrowUp" {
+		t.Fatalf("Config should have the values from the file, got: %+v", c)
+	}
+	if c.Sensitivity != 1 || len(c.GamepadAxes) == 0 {
+		t.Fatalf("Values missing from the file should keep their defaults, got: %+v", c)
+	}
+}
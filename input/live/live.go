@@ -0,0 +1,183 @@
+// Package live reads actions from the keyboard, mouse and gamepads with ebiten
+package live
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hvassaa/gaster/input"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+var mouseButtons = map[string]ebiten.MouseButton{
+	"Left":   ebiten.MouseButtonLeft,
+	"Right":  ebiten.MouseButtonRight,
+	"Middle": ebiten.MouseButtonMiddle,
+}
+
+var gamepadButtons = map[string]ebiten.StandardGamepadButton{
+	"A":     ebiten.StandardGamepadButtonRightBottom,
+	"B":     ebiten.StandardGamepadButtonRightRight,
+	"X":     ebiten.StandardGamepadButtonRightLeft,
+	"Y":     ebiten.StandardGamepadButtonRightTop,
+	"LB":    ebiten.StandardGamepadButtonFrontTopLeft,
+	"RB":    ebiten.StandardGamepadButtonFrontTopRight,
+	"LT":    ebiten.StandardGamepadButtonFrontBottomLeft,
+	"RT":    ebiten.StandardGamepadButtonFrontBottomRight,
+	"Back":  ebiten.StandardGamepadButtonCenterLeft,
+	"Start": ebiten.StandardGamepadButtonCenterRight,
+	"LS":    ebiten.StandardGamepadButtonLeftStick,
+	"RS":    ebiten.StandardGamepadButtonRightStick,
+	"Up":    ebiten.StandardGamepadButtonLeftTop,
+	"Down":  ebiten.StandardGamepadButtonLeftBottom,
+	"Left":  ebiten.StandardGamepadButtonLeftLeft,
+	"Right": ebiten.StandardGamepadButtonLeftRight,
+}
+
+var gamepadAxes = map[string]ebiten.StandardGamepadAxis{
+	"LeftX":  ebiten.StandardGamepadAxisLeftStickHorizontal,
+	"LeftY":  ebiten.StandardGamepadAxisLeftStickVertical,
+	"RightX": ebiten.StandardGamepadAxisRightStickHorizontal,
+	"RightY": ebiten.StandardGamepadAxisRightStickVertical,
+}
+
+type binding[T any] struct {
+	action input.Action
+	input  T
+}
+
+// Source polls the devices once per tick. The config is parsed when the
+// source is created, so call Rebind after changing it.
+type Source struct {
+	Config *input.Config
+	// KeyboardMouse reads the keys and mouse buttons, which only one player can use
+	KeyboardMouse bool
+	// MouseLook turns mouse movement into Turn and Look
+	MouseLook bool
+	// Gamepads limits the gamepads read to these, or reads all if it is nil.
+	// An empty list reads none.
+	Gamepads []ebiten.GamepadID
+	keys     []binding[ebiten.Key]
+	mouse    []binding[ebiten.MouseButton]
+	buttons  []binding[ebiten.StandardGamepadButton]
+	axes     []binding[input.Axis]
+	// cursorX and cursorY are where the cursor was last tick
+	cursorX, cursorY int
+	cursorKnown      bool
+}
+
+func New(cfg *input.Config) *Source {
+	s := &Source{Config: cfg, KeyboardMouse: true, MouseLook: true}
+	s.Rebind()
+	return s
+}
+
+// Rebind parses the bindings in the config. Unknown names are logged and skipped.
+func (s *Source) Rebind() {
+	s.keys, s.mouse, s.buttons, s.axes = nil, nil, nil, nil
+	for action, names := range s.Config.Keys {
+		for _, name := range names {
+			var k ebiten.Key
+			if err := k.UnmarshalText([]byte(name)); err != nil {
+				log.Printf("%v: %v", action, err)
+				continue
+			}
+			s.keys = append(s.keys, binding[ebiten.Key]{action, k})
+		}
+	}
+	for action, names := range s.Config.MouseButtons {
+		for _, name := range names {
+			if b, ok := mouseButtons[name]; ok {
+				s.mouse = append(s.mouse, binding[ebiten.MouseButton]{action, b})
+			} else {
+				log.Printf("%v: unknown mouse button %v", action, name)
+			}
+		}
+	}
+	for action, names := range s.Config.GamepadButtons {
+		for _, name := range names {
+			if b, ok := gamepadButtons[name]; ok {
+				s.buttons = append(s.buttons, binding[ebiten.StandardGamepadButton]{action, b})
+			} else {
+				log.Printf("%v: unknown gamepad button %v", action, name)
+			}
+		}
+	}
+	for _, a := range s.Config.GamepadAxes {
+		if _, ok := gamepadAxes[a.Axis]; ok {
+			s.axes = append(s.axes, binding[input.Axis]{input: a})
+		} else {
+			log.Printf("unknown gamepad axis %v", a.Axis)
+		}
+	}
+}
+
+// ResetCursor forgets where the cursor was, so the view does not jump when
+// the mouse has been used for something else
+func (s *Source) ResetCursor() {
+	s.cursorKnown = false
+}
+
+func (s *Source) Poll() input.Frame {
+	cfg := s.Config
+	values := input.Values{}
+	if s.KeyboardMouse {
+		for _, b := range s.keys {
+			if ebiten.IsKeyPressed(b.input) {
+				values.Set(b.action, 1)
+			}
+		}
+		for _, b := range s.mouse {
+			if ebiten.IsMouseButtonPressed(b.input) {
+				values.Set(b.action, 1)
+			}
+		}
+	}
+
+	gamepads := s.Gamepads
+	if gamepads == nil {
+		gamepads = ebiten.AppendGamepadIDs(nil)
+	}
+	for _, id := range gamepads {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for _, b := range s.buttons {
+			if ebiten.IsStandardGamepadButtonPressed(id, b.input) {
+				values.Set(b.action, 1)
+			}
+		}
+		for _, b := range s.axes {
+			v := cfg.ApplyDeadZone(ebiten.StandardGamepadAxisValue(id, gamepadAxes[b.input.Axis]))
+			action := b.input.Positive
+			if v < 0 {
+				action, v = b.input.Negative, -v
+			}
+			if cfg.InvertY && (action == input.LOOK_UP || action == input.LOOK_DOWN) {
+				action = invertLook(action)
+			}
+			values.Set(action, v)
+		}
+	}
+	f := values.Frame(*cfg)
+
+	x, y := ebiten.CursorPosition()
+	if s.KeyboardMouse && s.MouseLook && s.cursorKnown {
+		// moving the mouse right turns right, and up looks up
+		f.Turn += float64(x-s.cursorX) * cfg.Sensitivity / 2 * raycasting.DEG_TO_RAD
+		dy := float64(s.cursorY-y) * cfg.Sensitivity
+		if cfg.InvertY {
+			dy = -dy
+		}
+		f.Look += dy
+	}
+	s.cursorX, s.cursorY, s.cursorKnown = x, y, true
+	return f
+}
+
+func invertLook(a input.Action) input.Action {
+	if a == input.LOOK_UP {
+		return input.LOOK_DOWN
+	}
+	return input.LOOK_UP
+}
@@ -0,0 +1,84 @@
+package input
+
+import "github.com/hvassaa/gaster/raycasting"
+
+// Source gives the actions for each tick
+type Source interface {
+	Poll() Frame
+}
+
+// Step holds a frame for a number of ticks
+type Step struct {
+	Frame Frame
+	Ticks int
+}
+
+// Script is a source that plays steps, and then gives empty frames
+type Script struct {
+	Steps []Step
+	step  int
+	tick  int
+}
+
+func NewScript(steps ...Step) *Script {
+	return &Script{Steps: steps}
+}
+
+// advance skips the steps that have been held for all their ticks
+func (s *Script) advance() {
+	for s.step < len(s.Steps) && s.tick >= s.Steps[s.step].Ticks {
+		s.step++
+		s.tick = 0
+	}
+}
+
+func (s *Script) Poll() Frame {
+	if s.Done() {
+		return Frame{}
+	}
+	s.tick++
+	return s.Steps[s.step].Frame
+}
+
+func (s *Script) Done() bool {
+	s.advance()
+	return s.step >= len(s.Steps)
+}
+
+// Ticks is how many ticks the whole script takes
+func (s *Script) Ticks() int {
+	res := 0
+	for _, step := range s.Steps {
+		res += step.Ticks
+	}
+	return res
+}
+
+// Walk moves forward at full speed
+func Walk(ticks int) Step {
+	return Step{Frame{Move: 1}, ticks}
+}
+
+// Back moves backward at full speed
+func Back(ticks int) Step {
+	return Step{Frame{Move: -1}, ticks}
+}
+
+// StrafeRight moves right at full speed
+func StrafeRight(ticks int) Step {
+	return Step{Frame{Strafe: 1}, ticks}
+}
+
+func StrafeLeft(ticks int) Step {
+	return Step{Frame{Strafe: -1}, ticks}
+}
+
+// Turn turns right by degrees in a single tick, negative turns left
+func Turn(degrees float64) Step {
+	return Step{Frame{Turn: degrees * raycasting.DEG_TO_RAD}, 1}
+}
+
+// Wait does nothing
+func Wait(ticks int) Step {
+	return Step{Frame{}, ticks}
+}
@@ -0,0 +1,140 @@
+// Package layout describes how the screen is split into panes, each showing a view of a player
+package layout
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+)
+
+type Kind string
+
+const (
+	KIND_3D      Kind = "3d"
+	KIND_2D      Kind = "2d"
+	KIND_MINIMAP Kind = "minimap"
+)
+
+// SPLIT_MINIMAP is the size in pixels of the minimaps in split screen layouts
+const SPLIT_MINIMAP = 160
+
+// Rect is where a pane is on the screen. X, Y, W and H are fractions of the
+// screen size, so layouts follow the window size. PixelW and PixelH give a
+// fixed size in pixels instead of W and H when they are set.
+type Rect struct {
+	X, Y, W, H     float64
+	PixelW, PixelH int `json:",omitempty"`
+}
+
+type Pane struct {
+	Rect Rect
+	Kind Kind
+	// Player is the index of the player the pane shows the view of
+	Player int
+	// Circle gives minimap panes a round frame
+	Circle bool `json:",omitempty"`
+}
+
+type Layout struct {
+	Name string
+	// Panes are drawn in order, so later panes are on top
+	Panes []Pane
+}
+
+// Bounds is the rectangle of the screen covered by r
+func (r Rect) Bounds(screen image.Rectangle) image.Rectangle {
+	width, height := float64(screen.Dx()), float64(screen.Dy())
+	x0 := screen.Min.X + int(r.X*width)
+	y0 := screen.Min.Y + int(r.Y*height)
+	x1 := screen.Min.X + int((r.X+r.W)*width)
+	y1 := screen.Min.Y + int((r.Y+r.H)*height)
+	if r.PixelW > 0 {
+		x1 = x0 + r.PixelW
+	}
+	if r.PixelH > 0 {
+		y1 = y0 + r.PixelH
+	}
+	return image.Rect(x0, y0, x1, y1).Intersect(screen)
+}
+
+// Defaults are the original layouts: map and view side by side,
+// only the view, and the view with a minimap in the corner
+func Defaults() []Layout {
+	return []Layout{
+		{
+			Name: "split",
+			Panes: []Pane{
+				{Rect: Rect{0, 0, 0.5, 1, 0, 0}, Kind: KIND_2D},
+				{Rect: Rect{0.5, 0, 0.5, 1, 0, 0}, Kind: KIND_3D},
+			},
+		},
+		{
+			Name: "view",
+			Panes: []Pane{
+				{Rect: Rect{0, 0, 1, 1, 0, 0}, Kind: KIND_3D},
+			},
+		},
+		{
+			Name: "minimap",
+			Panes: []Pane{
+				{Rect: Rect{0, 0, 1, 1, 0, 0}, Kind: KIND_3D},
+				{Rect: Rect{0, 0, 0, 0, 300, 300}, Kind: KIND_MINIMAP, Circle: true},
+			},
+		},
+	}
+}
+
+// SplitScreen is a layout for n players sharing the screen, each with a view
+// and a minimap in its corner. Two players are above each other, and three or
+// four get a quarter each, with the map of the whole world in the fourth.
+func SplitScreen(n int) Layout {
+	l := Layout{Name: fmt.Sprintf("split %d", n)}
+	var views []Rect
+	switch {
+	case n <= 1:
+		views = []Rect{{0, 0, 1, 1, 0, 0}}
+	case n == 2:
+		views = []Rect{{0, 0, 1, 0.5, 0, 0}, {0, 0.5, 1, 0.5, 0, 0}}
+	default:
+		views = []Rect{{0, 0, 0.5, 0.5, 0, 0}, {0.5, 0, 0.5, 0.5, 0, 0}, {0, 0.5, 0.5, 0.5, 0, 0}, {0.5, 0.5, 0.5, 0.5, 0, 0}}
+		if n == 3 {
+			l.Panes = append(l.Panes, Pane{Rect: views[3], Kind: KIND_2D})
+		}
+		views = views[:n]
+	}
+	for i, r := range views {
+		minimap := Rect{X: r.X, Y: r.Y, PixelW: SPLIT_MINIMAP, PixelH: SPLIT_MINIMAP}
+		l.Panes = append(l.Panes,
+			Pane{Rect: r, Kind: KIND_3D, Player: i},
+			Pane{Rect: minimap, Kind: KIND_MINIMAP, Player: i, Circle: true},
+		)
+	}
+	return l
+}
+
+// Load reads a JSON list of layouts
+func Load(path string) ([]Layout, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var layouts []Layout
+	if err := json.Unmarshal(data, &layouts); err != nil {
+		return nil, err
+	}
+	if len(layouts) == 0 {
+		return nil, errors.New(path + ": no layouts")
+	}
+	for _, l := range layouts {
+		for _, p := range l.Panes {
+			switch p.Kind {
+			case KIND_3D, KIND_2D, KIND_MINIMAP:
+			default:
+				return nil, errors.New(path + ": unknown pane kind " + string(p.Kind) + " in layout " + l.Name)
+			}
+		}
+	}
+	return layouts, nil
+}
@@ -0,0 +1,68 @@
+package layout
+
+import (
+	"image"
+	"testing"
+)
+
+func TestBounds(t *testing.T) {
+	screen := image.Rect(0, 0, 1600, 800)
+
+	t.Run("Fractions follow the screen", func(t *testing.T) {
+		b := Rect{0.5, 0, 0.5, 1, 0, 0}.Bounds(screen)
+		if b != image.Rect(800, 0, 1600, 800) {
+			t.Fatalf("Right half should be (800,0)-(1600,800), got: %v", b)
+		}
+	})
+
+	t.Run("Pixel sizes are fixed", func(t *testing.T) {
+		b := Rect{0, 0, 0, 0, 300, 300}.Bounds(screen)
+		if b != image.Rect(0, 0, 300, 300) {
+			t.Fatalf("Fixed pane should be (0,0)-(300,300), got: %v", b)
+		}
+	})
+
+	t.Run("Panes are clipped to the screen", func(t *testing.T) {
+		b := Rect{0.9, 0.9, 0, 0, 300, 300}.Bounds(screen)
+		if b != image.Rect(1440, 720, 1600, 800) {
+			t.Fatalf("Pane should be clipped to (1440,720)-(1600,800), got: %v", b)
+		}
+	})
+}
+
+func TestLoadDefaultFile(t *testing.T) {
+	layouts, err := Load("../resources/layouts/default.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layouts) != 4 {
+		t.Fatalf("Should load 4 layouts, got: %v", len(layouts))
+	}
+}
+
+func TestSplitScreen(t *testing.T) {
+	screen := image.Rect(0, 0, 1600, 800)
+	for n := 2; n <= 4; n++ {
+		l := SplitScreen(n)
+		views := map[int]image.Rectangle{}
+		minimaps := map[int]bool{}
+		for _, p := range l.Panes {
+			switch p.Kind {
+			case KIND_3D:
+				views[p.Player] = p.Rect.Bounds(screen)
+			case KIND_MINIMAP:
+				minimaps[p.Player] = true
+			}
+		}
+		if len(views) != n || len(minimaps) != n {
+			t.Fatalf("%d players should have a view and a minimap each, got: %d views and %d minimaps", n, len(views), len(minimaps))
+		}
+		for i, a := range views {
+			for j, b := range views {
+				if i != j && a.Overlaps(b) {
+					t.Fatalf("Views of players %d and %d should not overlap, got: %v and %v", i, j, a, b)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package lighting
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// Face is the side of a wall block that a ray hits. North is the side
+// facing negative y, which is up in the 2D view.
+type Face int
+
+const (
+	NORTH Face = iota
+	SOUTH
+	EAST
+	WEST
+)
+
+type FogCurve int
+
+const (
+	FOG_NONE FogCurve = iota
+	FOG_LINEAR
+	FOG_EXP
+	FOG_EXP2
+)
+
+type Config struct {
+	// Ambient scales every color before anything else is applied
+	Ambient float64
+	// FaceBrightness is indexed by Face
+	FaceBrightness [4]float64
+	FogColor       color.RGBA
+	FogCurve       FogCurve
+	// FogStart and FogEnd are used by FOG_LINEAR, FogDensity by FOG_EXP and FOG_EXP2
+	FogStart, FogEnd, FogDensity float64
+}
+
+// Zone overrides the lighting config for a rectangle of map cells.
+// Bounds are cell indices and inclusive.
+type Zone struct {
+	MinX, MinY, MaxX, MaxY int
+	Config                 Config
+}
+
+func Default() Config {
+	return Config{
+		Ambient:        1,
+		FaceBrightness: [4]float64{0.8, 0.8, 1, 1},
+		FogColor:       color.RGBA{0, 0, 0, 255},
+		FogCurve:       FOG_NONE,
+	}
+}
+
+func FaceOf(ray raycasting.Ray) Face {
+	if ray.Dir == raycasting.HORIZONTAL {
+		// a ray going towards positive y hits the north side
+		if ray.Ang < raycasting.PI {
+			return NORTH
+		}
+		return SOUTH
+	}
+	if ray.Ang > raycasting.PI_HALF && ray.Ang < raycasting.PI_THREE_HALF {
+		return EAST
+	}
+	return WEST
+}
+
+func (z Zone) Contains(c raycasting.Coordinate, blockSize float64) bool {
+	x := int(math.Floor(c.X / blockSize))
+	y := int(math.Floor(c.Y / blockSize))
+	return x >= z.MinX && x <= z.MaxX && y >= z.MinY && y <= z.MaxY
+}
+
+// ConfigAt returns the config of the last zone containing c, or def if none do
+func ConfigAt(zones []Zone, def Config, c raycasting.Coordinate, blockSize float64) Config {
+	res := def
+	for _, z := range zones {
+		if z.Contains(c, blockSize) {
+			res = z.Config
+		}
+	}
+	return res
+}
+
+// FogAmount is how much of the fog color to mix in at the given distance,
+// between 0 (none) and 1 (only fog)
+func (c Config) FogAmount(dist float64) float64 {
+	var f float64
+	switch c.FogCurve {
+	case FOG_LINEAR:
+		if c.FogEnd <= c.FogStart {
+			return 0
+		}
+		f = (dist - c.FogStart) / (c.FogEnd - c.FogStart)
+	case FOG_EXP:
+		f = 1 - math.Exp(-c.FogDensity*dist)
+	case FOG_EXP2:
+		f = 1 - math.Exp(-(c.FogDensity*dist)*(c.FogDensity*dist))
+	default:
+		return 0
+	}
+	return max(min(f, 1), 0)
+}
+
+// Shade applies ambient, face brightness and fog to a wall color
+func (c Config) Shade(clr color.RGBA, face Face, dist float64) color.RGBA {
+	return c.ShadeLit(clr, face, dist, RGB{})
+}
+
+// ShadeLit is like Shade, but also adds light from point lights
+func (c Config) ShadeLit(clr color.RGBA, face Face, dist float64, light RGB) color.RGBA {
+	b := c.Ambient * c.FaceBrightness[face]
+	return c.shade(clr, light.Add(RGB{b, b, b}), dist)
+}
+
+// ShadeFlat applies ambient and fog to a floor or ceiling color
+func (c Config) ShadeFlat(clr color.RGBA, dist float64) color.RGBA {
+	return c.ShadeFlatLit(clr, dist, RGB{})
+}
+
+// ShadeFlatLit is like ShadeFlat, but also adds light from point lights
+func (c Config) ShadeFlatLit(clr color.RGBA, dist float64, light RGB) color.RGBA {
+	return c.shade(clr, light.Add(RGB{c.Ambient, c.Ambient, c.Ambient}), dist)
+}
+
+func (c Config) shade(clr color.RGBA, brightness RGB, dist float64) color.RGBA {
+	fog := c.FogAmount(dist)
+	mix := func(v, f uint8, b float64) uint8 {
+		lit := min(float64(v)*b, 255)
+		return uint8(lit*(1-fog) + float64(f)*fog)
+	}
+	return color.RGBA{
+		mix(clr.R, c.FogColor.R, brightness.R),
+		mix(clr.G, c.FogColor.G, brightness.G),
+		mix(clr.B, c.FogColor.B, brightness.B),
+		clr.A,
+	}
+}
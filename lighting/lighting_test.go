@@ -0,0 +1,87 @@
+package lighting
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+func TestFogAmount(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		dist   float64
+		want   float64
+	}{
+		{"None", Config{FogCurve: FOG_NONE, FogDensity: 1}, 1000, 0},
+		{"Linear before start", Config{FogCurve: FOG_LINEAR, FogStart: 100, FogEnd: 300}, 50, 0},
+		{"Linear halfway", Config{FogCurve: FOG_LINEAR, FogStart: 100, FogEnd: 300}, 200, 0.5},
+		{"Linear after end", Config{FogCurve: FOG_LINEAR, FogStart: 100, FogEnd: 300}, 400, 1},
+		{"Linear without range", Config{FogCurve: FOG_LINEAR, FogStart: 300, FogEnd: 100}, 200, 0},
+		{"Exp", Config{FogCurve: FOG_EXP, FogDensity: 0.01}, 100, 1 - math.Exp(-1)},
+		{"Exp at the camera", Config{FogCurve: FOG_EXP, FogDensity: 0.01}, 0, 0},
+		{"Exp2", Config{FogCurve: FOG_EXP2, FogDensity: 0.01}, 200, 1 - math.Exp(-4)},
+		{"Exp2 is thinner up close", Config{FogCurve: FOG_EXP2, FogDensity: 0.01}, 50, 1 - math.Exp(-0.25)},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.FogAmount(tt.dist); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("Fog at %v should be %v, got: %v", tt.dist, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestShade(t *testing.T) {
+	gray := color.RGBA{200, 100, 50, 255}
+	tests := []struct {
+		name   string
+		config Config
+		face   Face
+		dist   float64
+		want   color.RGBA
+	}{
+		{"Full brightness", Config{Ambient: 1, FaceBrightness: [4]float64{1, 1, 1, 1}}, NORTH, 0, gray},
+		{"Darker face", Config{Ambient: 1, FaceBrightness: [4]float64{1, 1, 0.5, 1}}, EAST, 0, color.RGBA{100, 50, 25, 255}},
+		{"Other faces are kept", Config{Ambient: 1, FaceBrightness: [4]float64{1, 1, 0.5, 1}}, WEST, 0, gray},
+		{"Ambient and face multiply", Config{Ambient: 0.5, FaceBrightness: [4]float64{1, 0.5, 1, 1}}, SOUTH, 0, color.RGBA{50, 25, 12, 255}},
+		{"Brightness is capped", Config{Ambient: 2, FaceBrightness: [4]float64{1, 1, 1, 1}}, NORTH, 0, color.RGBA{255, 200, 100, 255}},
+		{
+			"Fog is mixed in after brightness",
+			Config{Ambient: 1, FaceBrightness: [4]float64{0.5, 1, 1, 1}, FogColor: color.RGBA{0, 0, 250, 255}, FogCurve: FOG_LINEAR, FogEnd: 100},
+			NORTH, 50, color.RGBA{50, 25, 137, 255},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.Shade(gray, tt.face, tt.dist); got != tt.want {
+				t.Fatalf("Shaded color should be %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFaceOf(t *testing.T) {
+	tests := []struct {
+		name string
+		ray  raycasting.Ray
+		want Face
+	}{
+		{"Going down hits the north side", raycasting.Ray{Dir: raycasting.HORIZONTAL, Ang: raycasting.PI_HALF}, NORTH},
+		{"Going up hits the south side", raycasting.Ray{Dir: raycasting.HORIZONTAL, Ang: raycasting.PI_THREE_HALF}, SOUTH},
+		{"Going left hits the east side", raycasting.Ray{Dir: raycasting.VERTICAL, Ang: raycasting.PI}, EAST},
+		{"Going right hits the west side", raycasting.Ray{Dir: raycasting.VERTICAL, Ang: 0.1}, WEST},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FaceOf(tt.ray); got != tt.want {
+				t.Fatalf("Face should be %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package lighting
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// RGB is light per color channel, where 1 leaves a color unchanged
+type RGB struct {
+	R, G, B float64
+}
+
+func (a RGB) Add(b RGB) RGB {
+	return RGB{a.R + b.R, a.G + b.G, a.B + b.B}
+}
+
+func (a RGB) Scale(f float64) RGB {
+	return RGB{a.R * f, a.G * f, a.B * f}
+}
+
+type PointLight struct {
+	Coord     raycasting.Coordinate
+	Color     color.RGBA
+	Radius    float64
+	Intensity float64
+	// Flicker is how much the intensity can randomly drop each update, from 0 to 1
+	Flicker float64
+	// current flicker multiplier
+	flicker float64
+}
+
+func (l *PointLight) strength() float64 {
+	if l.Flicker == 0 {
+		return l.Intensity
+	}
+	return l.Intensity * l.flicker
+}
+
+// contribution is the light reaching c from l, ignoring walls
+func (l *PointLight) contribution(c raycasting.Coordinate) RGB {
+	dist := l.Coord.DistanceTo(c)
+	if dist >= l.Radius {
+		return RGB{}
+	}
+	falloff := 1 - dist/l.Radius
+	f := falloff * falloff * l.strength() / 255
+	return RGB{float64(l.Color.R) * f, float64(l.Color.G) * f, float64(l.Color.B) * f}
+}
+
+// Lights accumulates point lights on a map, with walls blocking the light.
+// Light on floor cells is cached, so Update must be called after lights are
+// moved, added or removed, or the map changes.
+type Lights struct {
+	Lights    []*PointLight
+	BlockSize float64
+	mab       [][]raycasting.WallType
+	cells     [][]RGB
+}
+
+func NewLights(blockSize float64, mab [][]raycasting.WallType) *Lights {
+	return &Lights{
+		BlockSize: blockSize,
+		mab:       mab,
+	}
+}
+
+// SetMap changes the map blocking the light
+func (ls *Lights) SetMap(mab [][]raycasting.WallType) {
+	ls.mab = mab
+	ls.cells = nil
+}
+
+func (ls *Lights) Add(l *PointLight) {
+	l.flicker = 1
+	ls.Lights = append(ls.Lights, l)
+}
+
+func (ls *Lights) Remove(l *PointLight) {
+	for i, e := range ls.Lights {
+		if e == l {
+			ls.Lights = append(ls.Lights[:i], ls.Lights[i+1:]...)
+			return
+		}
+	}
+}
+
+// Update advances flickering and recomputes the light on floor cells
+func (ls *Lights) Update(rng *rand.Rand) {
+	for _, l := range ls.Lights {
+		if l.Flicker > 0 {
+			target := 1 - l.Flicker*rng.Float64()
+			// ease towards the target, so the light does not strobe
+			l.flicker = l.flicker*0.6 + target*0.4
+		}
+	}
+
+	if len(ls.cells) != len(ls.mab) {
+		ls.cells = make([][]RGB, len(ls.mab))
+	}
+	for y, row := range ls.mab {
+		if len(ls.cells[y]) != len(row) {
+			ls.cells[y] = make([]RGB, len(row))
+		}
+		for x, wallType := range row {
+			if wallType != 0 {
+				ls.cells[y][x] = RGB{}
+				continue
+			}
+			center := raycasting.Coordinate{
+				X: (float64(x) + 0.5) * ls.BlockSize,
+				Y: (float64(y) + 0.5) * ls.BlockSize,
+			}
+			ls.cells[y][x] = ls.At(center)
+		}
+	}
+}
+
+// At is the light reaching c from all lights that can see it
+func (ls *Lights) At(c raycasting.Coordinate) RGB {
+	var res RGB
+	for _, l := range ls.Lights {
+		if l.Coord.DistanceTo(c) >= l.Radius {
+			continue
+		}
+		if !raycasting.HasLineOfSight(l.Coord, c, ls.BlockSize, ls.mab) {
+			continue
+		}
+		res = res.Add(l.contribution(c))
+	}
+	return res
+}
+
+// Wall is the light reaching a wall hit by ray
+func (ls *Lights) Wall(ray raycasting.Ray) RGB {
+	return ls.At(ray.Coord)
+}
+
+// Floor is the cached light on the floor cell containing c
+func (ls *Lights) Floor(c raycasting.Coordinate) RGB {
+	x := int(math.Floor(c.X / ls.BlockSize))
+	y := int(math.Floor(c.Y / ls.BlockSize))
+	if y < 0 || y >= len(ls.cells) || x < 0 || x >= len(ls.cells[y]) {
+		return RGB{}
+	}
+	return ls.cells[y][x]
+}
@@ -0,0 +1,65 @@
+package lighting
+
+import (
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+func TestLightIsBlockedByWalls(t *testing.T) {
+	blockSize := 10.
+	// 1 1 1 1 1
+	// 1 0 1 0 1
+	// 1 1 1 1 1
+	m := make([][]raycasting.WallType, 3)
+	for i := range m {
+		m[i] = []raycasting.WallType{1, 1, 1, 1, 1}
+	}
+	m[1][1] = 0
+	m[1][3] = 0
+
+	ls := NewLights(blockSize, m)
+	ls.Add(&PointLight{
+		Coord:     raycasting.Coordinate{X: 15, Y: 15},
+		Color:     color.RGBA{255, 255, 255, 255},
+		Radius:    100,
+		Intensity: 1,
+	})
+	ls.Update(rand.New(rand.NewSource(0)))
+
+	t.Run("Lit cell", func(t *testing.T) {
+		l := ls.Floor(raycasting.Coordinate{X: 15, Y: 15})
+		if l.R <= 0 {
+			t.Fatalf("Cell with the light should be lit, got: %v", l)
+		}
+	})
+
+	t.Run("Cell behind wall", func(t *testing.T) {
+		l := ls.Floor(raycasting.Coordinate{X: 35, Y: 15})
+		if l.R != 0 {
+			t.Fatalf("Cell behind a wall should be dark, got: %v", l)
+		}
+	})
+
+	t.Run("Wall facing the light", func(t *testing.T) {
+		ray, err := raycasting.CastRay(raycasting.Coordinate{X: 15, Y: 15}, 0, blockSize, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l := ls.Wall(*ray); l.R <= 0 {
+			t.Fatalf("Wall facing the light should be lit, got: %v", l)
+		}
+	})
+
+	t.Run("Wall facing away from the light", func(t *testing.T) {
+		ray, err := raycasting.CastRay(raycasting.Coordinate{X: 35, Y: 15}, raycasting.PI, blockSize, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l := ls.Wall(*ray); l.R != 0 {
+			t.Fatalf("Wall facing away from the light should be dark, got: %v", l)
+		}
+	})
+}
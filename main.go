@@ -1,15 +1,41 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"image"
+	"image/color"
 	"log"
-	"math"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hvassaa/gaster/automap"
+	"github.com/hvassaa/gaster/capture"
+	"github.com/hvassaa/gaster/console"
+	"github.com/hvassaa/gaster/console/view"
+	"github.com/hvassaa/gaster/display"
+	"github.com/hvassaa/gaster/enemy"
+	"github.com/hvassaa/gaster/hud"
+	"github.com/hvassaa/gaster/input"
+	"github.com/hvassaa/gaster/input/live"
+	"github.com/hvassaa/gaster/layout"
+	"github.com/hvassaa/gaster/lighting"
+	"github.com/hvassaa/gaster/maps"
+	"github.com/hvassaa/gaster/netplay"
 	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/postfx"
+	"github.com/hvassaa/gaster/projectile"
 	"github.com/hvassaa/gaster/raycasting"
 	"github.com/hvassaa/gaster/rendering"
+	"github.com/hvassaa/gaster/replay"
+	"github.com/hvassaa/gaster/save"
+	"github.com/hvassaa/gaster/scene"
+	"github.com/hvassaa/gaster/settings"
+	"github.com/hvassaa/gaster/sim"
+	"github.com/hvassaa/gaster/weapon"
 )
 
 const (
@@ -18,226 +44,756 @@ const (
 	BLOCK_SIZE       = 40.
 	BLOCKS_X     int = WORLD_WIDTH / BLOCK_SIZE
 	BLOCKS_Y     int = WORLD_HEIGHT / BLOCK_SIZE
-	FOV              = 60
 	NO_OF_RAYS       = 61
-	DEG_BOUNDS       = (NO_OF_RAYS - 1) / 2
-	DEG_PER_RAY      = FOV / (NO_OF_RAYS - 1.)
 )
 
 type Game struct {
-	player           *player.Player
-	mab              [][]raycasting.WallType
-	represntation    int
-	cursorX, cursorY int
-	Paused           bool
-	r3d              rendering.Renderer
-	r2d              rendering.Renderer
-	updateRenders    bool
+	world      *sim.World
+	layouts    []layout.Layout
+	layoutIdx  int
+	panes      []pane
+	screenSize image.Point
+	settings   settings.Settings
+	input      *live.Source
+	// source gives the actions, which is input unless a replay is playing
+	source         input.Source
+	lastFrame      input.Frame
+	updateRenders  bool
+	lightingZones  []lighting.Zone
+	sky            *ebiten.Image
+	outdoor        [][]bool
+	capturer       *capture.Capturer
+	takeScreenshot bool
+	minimap        bool
+	minimapZoom    float64
+	explored       *automap.Explored
+	postfx         *postfx.Pipeline
+	hud            *hud.HUD
+	// inspect shows the ray under the cursor, whose details are in inspected
+	inspect   bool
+	inspected string
+	inspector *hud.Text
+	colorRays bool
+	console   *view.View
+	scenes    *scene.Manager
+	// mapID is replay.DEMO_MAP or the path of the loaded map
+	mapID     string
+	recording *replay.Replay
+	replayDir string
+	demoFile  string
+	saveDir   string
+	// client is set while playing online, and server while hosting
+	client     *netplay.Client
+	server     *netplay.Server
+	serverStop chan struct{}
+	// locals are the players sharing the screen with the first one
+	locals     []*local
+	enemyTypes map[string]*enemy.Type
+	// weaponTypes are carried by every player, and muzzles light up the shots and explosions
+	weaponTypes     []*weapon.Type
+	projectileTypes map[string]*projectile.Type
+	muzzles         []*muzzle
+	playback        *replay.Playback
+	// afterPlayback is called when playback ends or is stopped
+	afterPlayback func()
+	// quit is returned from Update to end the game
+	quit         error
+	settingsFile string
+	// frames are rendered here, and then scaled onto the window
+	frame                      *ebiten.Image
+	frameScale, frameX, frameY float64
 }
 
+// pane is a layout pane with the renderer drawing it
+type pane struct {
+	layout.Pane
+	renderer rendering.Renderer
+	screen   *ebiten.Image
+}
+
+func (g *Game) newRenderer2D(screen *ebiten.Image, p *player.Player) *rendering.Renderer2D {
+	// loaded maps can have another size than the default world
+	worldWidth := float64(len(g.world.Map[0])) * BLOCK_SIZE
+	worldHeight := float64(len(g.world.Map)) * BLOCK_SIZE
+	r2d := rendering.NewRenderer2D(screen, worldWidth, worldHeight, BLOCK_SIZE, p, g.world.Map)
+	r2d.Explored = g.explored
+	r2d.ColorRays = g.colorRays
+	return r2d
+}
+
+// buildPanes creates renderers for the panes of the current layout.
+// Panes of players that are not playing are left out.
+func (g *Game) buildPanes(screen *ebiten.Image) {
+	g.panes = g.panes[:0]
+	players := g.players()
+	for _, p := range g.currentLayout().Panes {
+		bounds := p.Rect.Bounds(screen.Bounds())
+		if bounds.Empty() || p.Player < 0 || p.Player >= len(players) {
+			continue
+		}
+		pl := players[p.Player]
+		sub := screen.SubImage(bounds).(*ebiten.Image)
+		var r rendering.Renderer
+		switch p.Kind {
+		case layout.KIND_3D:
+			r = g.newRenderer3D(sub, pl)
+		case layout.KIND_2D:
+			r = g.newRenderer2D(sub, pl)
+		case layout.KIND_MINIMAP:
+			r2d := g.newRenderer2D(sub, pl)
+			r2d.Minimap = g.minimap
+			r2d.Zoom = g.minimapZoom
+			if p.Circle {
+				r2d.Frame = rendering.FRAME_CIRCLE
+			}
+			r = r2d
+		}
+		g.panes = append(g.panes, pane{p, r, sub})
+	}
+	g.screenSize = screen.Bounds().Size()
+}
+
+func (g *Game) newRenderer3D(screen *ebiten.Image, p *player.Player) *rendering.Renderer3D {
+	r3d := rendering.NewRenderer3D(screen, p, NO_OF_RAYS, BLOCK_SIZE)
+	r3d.LightingZones = g.lightingZones
+	r3d.Lights = g.world.Lights
+	r3d.Sky = g.sky
+	r3d.Outdoor = g.outdoor
+	return r3d
+}
+
+// Update handles the keys that work in every scene, and then updates the current scene
 func (g *Game) Update() error {
-	if ebiten.IsKeyPressed(ebiten.KeyBackspace) {
-		os.Exit(0)
+	if inpututil.IsKeyJustPressed(ebiten.KeyF11) {
+		g.settings.Display.Fullscreen = !ebiten.IsFullscreen()
+		ebiten.SetFullscreen(g.settings.Display.Fullscreen)
 	}
 
-	if ebiten.IsKeyPressed(ebiten.KeyEscape) {
-		g.Paused = true
-		ebiten.SetCursorMode(ebiten.CursorModeVisible)
-	} else if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
-		g.Paused = false
-		ebiten.SetCursorMode(ebiten.CursorModeCaptured)
-		g.cursorX, g.cursorY = ebiten.CursorPosition()
+	// screenshots and recordings are taken at the end of Draw
+	if inpututil.IsKeyJustPressed(ebiten.KeyF12) {
+		g.takeScreenshot = true
+	}
+	// a full recording is saved, so it does not grow forever
+	if inpututil.IsKeyJustPressed(ebiten.KeyF10) || g.capturer.Full() {
+		g.toggleCapture()
+	}
+
+	// the server is heard and kept informed in every scene, so pausing does not drop the connection
+	g.pollServer()
+	err := g.scenes.Update()
+	if g.client != nil {
+		if err := g.client.KeepAlive(); err != nil {
+			log.Printf("could not reach the server: %v", err)
+		}
 	}
+	return err
+}
 
-	if g.Paused {
+func (g *Game) toggleCapture() {
+	path, err := g.capturer.Toggle()
+	if err != nil {
+		log.Printf("recording failed: %v", err)
+	} else if g.capturer.Recording() {
+		log.Printf("recording to %v", path)
+	} else if n := g.capturer.Dropped(); n > 0 {
+		log.Printf("recording saved to %v, without %d frames the encoder could not keep up with", path, n)
+	} else {
+		log.Printf("recording saved to %v", path)
+	}
+}
+
+// updatePlay moves the player and changes the view, while the game is being played
+func (g *Game) updatePlay(scenes *scene.Manager) error {
+	// the console takes the keyboard while it is open
+	if g.console.Update() {
 		return nil
 	}
-	if ebiten.IsKeyPressed(ebiten.Key1) {
-		g.represntation = 0
-		g.updateRenders = true
-	} else if ebiten.IsKeyPressed(ebiten.Key2) {
-		g.represntation = 1
-		g.updateRenders = true
-	} else if ebiten.IsKeyPressed(ebiten.Key3) {
-		g.represntation = 2
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) {
+		return ebiten.Termination
+	}
+
+	// the ray inspector needs the cursor, so mouse look is off while it is shown
+	if inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		g.toggleInspect()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF4) {
+		g.colorRays = !g.colorRays
 		g.updateRenders = true
 	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF1) {
+		g.hud.Visible = !g.hud.Visible
+	}
+	// number keys switch between layouts
+	for i := range g.layouts {
+		if i < 9 && inpututil.IsKeyJustPressed(ebiten.Key1+ebiten.Key(i)) {
+			g.layoutIdx = i
+			g.updateRenders = true
+		}
+	}
 
-	// calculate mouse deltas
-	newCursorX, newCursorY := ebiten.CursorPosition()
-	deltaX := g.cursorX - newCursorX
-	deltaY := g.cursorY - newCursorY
+	// a replay being played back is not recorded again
+	if inpututil.IsKeyJustPressed(ebiten.KeyF9) && g.playback == nil {
+		g.toggleRecording()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+		if err := g.saveGame(save.QUICK_SLOT); err != nil {
+			log.Printf("quick save failed: %v", err)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF8) {
+		if err := g.loadGame(save.QUICK_SLOT); err != nil {
+			log.Printf("quick load failed: %v", err)
+		}
+	}
 
-	// Update y, to look up or down
-	if deltaY != 0 && g.cursorY != 0 {
-		g.player.IncreaseHozAngle(float64(deltaY))
+	// a replay drives the game until it is done, or escape is pressed
+	if g.playback != nil && (g.playback.Done() || inpututil.IsKeyJustPressed(ebiten.KeyEscape)) {
+		g.stopPlayback()
+		return nil
 	}
 
-	// update mouse position
-	g.cursorX, g.cursorY = newCursorX, newCursorY
+	g.input.MouseLook = !g.inspect
+	f := g.source.Poll()
+	paused := g.pollLocals()
+	if g.playback == nil {
+		defer func() { g.lastFrame = f }()
+		paused = paused || f.JustPressed(g.lastFrame, input.PAUSE)
+	}
+	g.step(f)
+	// every player moves in the tick pausing was pressed in, like in any other
+	if paused {
+		scenes.Push(g.newPauseMenu())
+	}
+	return nil
+}
 
-	// look left or right with mouse
-	xMultiplier := 0.
-	if deltaX != 0 {
-		xMultiplier += float64(deltaX) / -2. * raycasting.DEG_TO_RAD
+// step advances the game by a tick, with the actions in f
+func (g *Game) step(f input.Frame) {
+	if g.recording != nil {
+		g.recording.Add(f)
 	}
-	if deltaX != 0 {
-		g.player.IncreaseAngle(xMultiplier)
-	} 
+	g.postfx.Update()
 
-	// move forward or backwards with keyboard
-	if ebiten.IsKeyPressed(ebiten.KeyW) {
-		ray, err := raycasting.CastRay(*g.player.Coord, g.player.Angle, BLOCK_SIZE, g.mab)
-		if err == nil && ray.Coord.DistanceTo(*g.player.Coord) > BLOCK_SIZE/2 {
-			g.player.Move(1)
-		}
-	} else if ebiten.IsKeyPressed(ebiten.KeyS) {
-		ray, err := raycasting.CastRay(*g.player.Coord, raycasting.NormalizeAngle(g.player.Angle+raycasting.PI), BLOCK_SIZE, g.mab)
-		if err == nil && ray.Coord.DistanceTo(*g.player.Coord) > BLOCK_SIZE/2 {
-			g.player.Move(-1)
+	// the minimap in the corner can follow the player, and zoom
+	if g.world.JustPressed(f, input.MINIMAP) {
+		g.minimap = !g.minimap
+		g.updateRenders = true
+	}
+	if g.world.JustPressed(f, input.ZOOM_IN) {
+		g.minimapZoom = min(g.minimapZoom*2, 8)
+		g.updateRenders = true
+	} else if g.world.JustPressed(f, input.ZOOM_OUT) {
+		g.minimapZoom = max(g.minimapZoom/2, 0.25)
+		g.updateRenders = true
+	}
+	g.stepLocals()
+	g.world.Step(f)
+	g.explore()
+	g.flashHits()
+	g.lightShots()
+	if g.client != nil {
+		if err := g.client.Send(f); err != nil {
+			log.Printf("could not send input: %v", err)
 		}
 	}
+}
 
-	// strafe
-	if ebiten.IsKeyPressed(ebiten.KeyA) {
-		angle := -raycasting.PI_HALF
-		ray, err := raycasting.CastRay(*g.player.Coord, raycasting.NormalizeAngle(g.player.Angle+angle), BLOCK_SIZE, g.mab)
-		if err == nil && ray.Coord.DistanceTo(*g.player.Coord) > BLOCK_SIZE/2 {
-			g.player.MoveWithAngle(1, angle)
+// explore marks the cells every player sees, so they explore the map together
+func (g *Game) explore() {
+	for _, p := range g.players() {
+		rays := raycasting.CastRays(*p.Coord, p.Angle, g.settings.View.FOV*raycasting.DEG_TO_RAD, NO_OF_RAYS, BLOCK_SIZE, g.world.Map)
+		g.explored.MarkRays(*p.Coord, rays, BLOCK_SIZE)
+	}
+}
+
+// startState is the state a replay starts from
+func (g *Game) startState() replay.Start {
+	p := g.world.Player
+	a := g.world.Arsenal
+	return replay.Start{
+		X: p.Coord.X, Y: p.Coord.Y, Angle: p.Angle, HozAngle: p.HozAngle, Speed: p.Speed, Noclip: g.world.Noclip,
+		Health: p.Health, Weapons: a.States(), Weapon: a.Current,
+	}
+}
+
+// toggleRecording starts recording, or saves the recording. The random
+// numbers are reseeded, so the recording knows the seed.
+func (g *Game) toggleRecording() {
+	if g.recording == nil {
+		if len(g.locals) > 0 {
+			log.Printf("only one player can be recorded")
+			return
 		}
-	} else if ebiten.IsKeyPressed(ebiten.KeyD) {
-		angle := raycasting.PI_HALF
-		ray, err := raycasting.CastRay(*g.player.Coord, raycasting.NormalizeAngle(g.player.Angle+angle), BLOCK_SIZE, g.mab)
-		if err == nil && ray.Coord.DistanceTo(*g.player.Coord) > BLOCK_SIZE/2 {
-			g.player.MoveWithAngle(1, angle)
+		seed := time.Now().UnixNano()
+		g.world.Seed(seed)
+		// the replay only knows the map, so the enemies start over from it
+		g.spawnEnemies()
+		// weapons in the middle of firing or reloading would start differently when played back
+		g.restore(g.startState())
+		g.recording = replay.New(seed, g.mapID, g.startState())
+		log.Printf("recording input")
+		return
+	}
+	r := g.recording
+	g.recording = nil
+	if err := os.MkdirAll(g.replayDir, 0o755); err != nil {
+		log.Printf("could not save replay: %v", err)
+		return
+	}
+	path := filepath.Join(g.replayDir, "replay-"+time.Now().Format(capture.TIMESTAMP_LAYOUT)+".json")
+	if err := r.Save(path); err != nil {
+		log.Printf("could not save replay: %v", err)
+		return
+	}
+	log.Printf("replay of %d ticks saved to %v", len(r.Frames), path)
+}
+
+// startPlayback puts the game in the state the replay was recorded from,
+// and plays it. after is called when it ends.
+func (g *Game) startPlayback(r *replay.Replay, after func()) error {
+	if g.client != nil {
+		return errors.New("cannot play a replay while online")
+	}
+	if r.Map != g.mapID {
+		m := maps.Demo(BLOCKS_X, BLOCKS_Y)
+		if r.Map != replay.DEMO_MAP {
+			var err error
+			if m, err = maps.Load(r.Map); err != nil {
+				return err
+			}
 		}
+		g.setMap(r.Map, m)
 	}
+	g.restore(r.Start)
+	g.world.Seed(r.Seed)
+	g.spawnEnemies()
+	g.recording = nil
+	g.playback = replay.NewPlayback(r)
+	g.source = g.playback
+	g.afterPlayback = after
+	return nil
+}
 
-	if ebiten.IsKeyPressed(ebiten.KeyQ) {
-		g.player.IncreaseAngle(-0.04)
-	} else if ebiten.IsKeyPressed(ebiten.KeyE) {
-		g.player.IncreaseAngle(0.04)
+func (g *Game) restore(s replay.Start) {
+	p := g.world.Player
+	p.Coord.X, p.Coord.Y, p.Angle, p.HozAngle, p.Speed = s.X, s.Y, s.Angle, s.HozAngle, s.Speed
+	p.Health = s.Health
+	if p.Health == 0 {
+		p.Health = p.MaxHealth
 	}
+	g.world.Noclip = s.Noclip
+	g.world.Arsenal.Restore(s.Weapons, s.Weapon)
+}
 
-	return nil
+func (g *Game) stopPlayback() {
+	log.Printf("replay stopped after %d of %d ticks", g.playback.Tick(), len(g.playback.Replay.Frames))
+	g.playback = nil
+	g.source = g.input
+	g.input.ResetCursor()
+	if g.afterPlayback != nil {
+		g.afterPlayback()
+		g.afterPlayback = nil
+	}
+}
+
+func (g *Game) toggleInspect() {
+	g.inspect = !g.inspect
+	g.updateRenders = true
+	if g.inspect {
+		ebiten.SetCursorMode(ebiten.CursorModeVisible)
+		g.hud.Add(g.inspector, hud.TOP_RIGHT)
+	} else {
+		ebiten.SetCursorMode(ebiten.CursorModeCaptured)
+		g.hud.Remove(g.inspector)
+	}
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
-	coords := make([]raycasting.Coordinate, NO_OF_RAYS)
-	rayDistances := make([]float32, NO_OF_RAYS)
-	directions := make([]raycasting.Direction, NO_OF_RAYS)
-	rays := make([]raycasting.Ray, NO_OF_RAYS)
-	for i := -DEG_BOUNDS; i <= DEG_BOUNDS; i++ {
-		rayAngle := raycasting.NormalizeAngle(g.player.Angle + (float64(i) * DEG_PER_RAY * raycasting.DEG_TO_RAD))
-		ray, err := raycasting.CastRay(*g.player.Coord, rayAngle, BLOCK_SIZE, g.mab)
-		if err != nil {
-			continue
+	opts := g.settings.Display
+	width, height := opts.RenderSize(screen.Bounds().Dx(), screen.Bounds().Dy())
+	if g.frame == nil || g.frame.Bounds().Dx() != width || g.frame.Bounds().Dy() != height {
+		if g.frame != nil {
+			g.frame.Dispose()
 		}
-		coords[i+DEG_BOUNDS] = ray.Coord
-		noFish := math.Cos(raycasting.NormalizeAngle(rayAngle - g.player.Angle))
-		rayDistances[i+DEG_BOUNDS] = float32(ray.Coord.DistanceTo(*g.player.Coord) * noFish)
-		directions[i+DEG_BOUNDS] = ray.Dir
-		rays[i+DEG_BOUNDS] = *ray
+		g.frame = ebiten.NewImage(width, height)
+	}
+	g.frame.Clear()
+	g.scenes.Draw(g.frame)
+
+	g.frameScale, g.frameX, g.frameY = opts.Placement(width, height, screen.Bounds().Dx(), screen.Bounds().Dy())
+	screen.Fill(color.Black)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(g.frameScale, g.frameScale)
+	op.GeoM.Translate(g.frameX, g.frameY)
+	if opts.Scaling == display.SCALING_FILTERED {
+		op.Filter = ebiten.FilterLinear
+	}
+	screen.DrawImage(g.frame, op)
+
+	if g.takeScreenshot || g.capturer.Recording() {
+		g.capture(screen)
+	}
+}
+
+// drawFrame draws all panes of the current layout
+func (g *Game) drawFrame(screen *ebiten.Image) {
+	// every player sees their own rays
+	players := g.players()
+	rays := make([][]raycasting.Ray, len(players))
+	for i, p := range players {
+		rays[i] = raycasting.CastRays(*p.Coord, p.Angle, g.settings.View.FOV*raycasting.DEG_TO_RAD, NO_OF_RAYS, BLOCK_SIZE, g.world.Map)
 	}
 
-	if g.represntation == 0 {
-		if g.updateRenders {
-			width := screen.Bounds().Dx()
-			height := screen.Bounds().Dy()
-			twoDScreen := screen.SubImage(image.Rect(0, 0, width/2, height)).(*ebiten.Image)
-			threeDScreen := screen.SubImage(image.Rect(width/2, 0, width, height)).(*ebiten.Image)
-			g.r2d = rendering.NewRenderer2D(twoDScreen, WORLD_WIDTH, WORLD_HEIGHT, BLOCK_SIZE, g.player, g.mab)
-			g.r3d = rendering.NewRenderer3D(threeDScreen, g.player, NO_OF_RAYS, BLOCK_SIZE)
-			g.updateRenders = false
+	// the renderers cache the size of their part of the screen
+	if g.updateRenders || screen.Bounds().Size() != g.screenSize {
+		g.buildPanes(screen)
+		g.updateRenders = false
+	}
+	if g.inspect {
+		g.inspectRays(rays[0])
+	}
+	for _, p := range g.panes {
+		sprites := g.spritesFor(p.Player)
+		switch r := p.renderer.(type) {
+		case *rendering.Renderer3D:
+			r.FOV = g.settings.View.FOV * raycasting.DEG_TO_RAD
+			r.Sprites = sprites
+			r.Gun = g.gunOf(p.Player)
+		case *rendering.Renderer2D:
+			r.Sprites = sprites
+		}
+	}
+	hasView := false
+	for _, p := range g.panes {
+		p.renderer.Render(rays[p.Player])
+		if p.Kind == layout.KIND_3D {
+			g.postfx.ShowPlayer(p.Player)
+			g.postfx.Apply(p.screen)
+			h := g.hudOf(p.Player)
+			h.Visible = g.hud.Visible
+			h.Draw(p.screen)
+			hasView = true
 		}
+	}
+	// without a 3D view the HUD goes on top of everything
+	if !hasView {
+		g.hud.Draw(screen)
+	}
+	g.console.Draw(screen)
+}
 
-		g.r3d.Render(rays)
-		g.r2d.Render(rays)
-	} else if g.represntation == 1 {
-		if g.updateRenders {
-			g.r3d = rendering.NewRenderer3D(screen, g.player, NO_OF_RAYS, BLOCK_SIZE)
-			g.updateRenders = false
+// inspectRays finds the ray of the first player under the cursor in any of its panes,
+// and highlights it in all of them
+func (g *Game) inspectRays(rays []raycasting.Ray) {
+	cursor := g.frameCursor()
+	x, y := cursor.X, cursor.Y
+	highlight, found := rendering.NO_HIGHLIGHT, false
+	// panes are drawn in order, so the last one under the cursor is on top
+	for i := len(g.panes) - 1; i >= 0; i-- {
+		p := g.panes[i]
+		if p.Player != 0 {
+			continue
+		}
+		switch r := p.renderer.(type) {
+		case *rendering.Renderer3D:
+			highlight, found = r.ColumnAt(x, y)
+		case *rendering.Renderer2D:
+			highlight, found = r.RayAt(x, y, rays)
 		}
-		g.r3d.Render(rays)
-	} else if g.represntation == 2 {
-		if g.updateRenders {
-			twoDScreen := screen.SubImage(image.Rect(0, 0, 300, 300)).(*ebiten.Image)
-			g.r2d = rendering.NewRenderer2D(twoDScreen, WORLD_WIDTH, WORLD_HEIGHT, BLOCK_SIZE, g.player, g.mab)
-			g.r3d = rendering.NewRenderer3D(screen, g.player, NO_OF_RAYS, BLOCK_SIZE)
-			g.updateRenders = false
+		if found {
+			break
 		}
+	}
+	if !found || highlight >= len(rays) {
+		highlight = rendering.NO_HIGHLIGHT
+	}
 
-		g.r3d.Render(rays)
-		g.r2d.Render(rays)
+	g.inspected = "no ray under the cursor"
+	if highlight != rendering.NO_HIGHLIGHT {
+		// only the 3D view knows the texture column, so this is replaced if there is one
+		g.inspected = rendering.RayInfo{Index: highlight, Ray: rays[highlight]}.String()
+	}
+	for _, p := range g.panes {
+		if p.Player != 0 {
+			continue
+		}
+		switch r := p.renderer.(type) {
+		case *rendering.Renderer3D:
+			r.Highlight = highlight
+			if highlight != rendering.NO_HIGHLIGHT {
+				g.inspected = r.Inspect(rays, highlight).String()
+			}
+		case *rendering.Renderer2D:
+			r.Highlight = highlight
+		}
 	}
 }
 
-func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return 1600, 800
+// newHUD creates the HUD with the built in widgets for p
+func newHUD(s settings.HUD, p *player.Player) *hud.HUD {
+	face := hud.DefaultFace()
+	if s.Font != "" {
+		f, err := hud.LoadFace(s.Font, s.FontSize)
+		if err != nil {
+			log.Printf("could not load HUD font, using the default: %v", err)
+		} else {
+			face = f
+		}
+	}
+	h := hud.New(face)
+	h.Visible = s.Show
+	white := color.RGBA{255, 255, 255, 220}
+	h.Add(&hud.Crosshair{Length: 6, Gap: 3, Color: white}, hud.CENTER)
+	h.Add(hud.FPS(), hud.TOP_LEFT)
+	h.Add(hud.PlayerInfo(p), hud.TOP_LEFT)
+	h.Add(&hud.Slot{
+		Label: "HEALTH",
+		Value: func() int { return p.Health },
+		Max:   func() int { return p.MaxHealth },
+		Color: color.RGBA{220, 60, 60, 255},
+	}, hud.BOTTOM_LEFT)
+	return h
 }
 
-func makeStandardMap() [][]raycasting.WallType {
-	m := make([][]raycasting.WallType, BLOCKS_Y)
-	for i := range m {
-		m[i] = make([]raycasting.WallType, BLOCKS_X)
-	}
-	for y := 0; y < BLOCKS_Y; y++ {
-		for x := 0; x < BLOCKS_X; x++ {
-			if x == 0 || x == BLOCKS_X-1 || y == 0 || y == BLOCKS_Y-1 {
-				m[y][x] = 1
-			}
+func (g *Game) capture(screen *ebiten.Image) {
+	img := image.NewRGBA(screen.Bounds())
+	screen.ReadPixels(img.Pix)
+	if g.takeScreenshot {
+		g.takeScreenshot = false
+		path, err := g.capturer.Screenshot(img)
+		if err != nil {
+			log.Printf("screenshot failed: %v", err)
+		} else {
+			log.Printf("screenshot saved to %v", path)
 		}
 	}
+	// the recording keeps img, which is a new copy every frame
+	g.capturer.Frame(img)
+}
 
-	return m
+func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
+	return outsideWidth, outsideHeight
 }
 
 func main() {
+	captureDir := flag.String("capture-dir", "./captures", "directory for screenshots (F12) and recordings (F10)")
+	captureGIF := flag.Bool("capture-gif", false, "record an animated GIF instead of a PNG sequence")
+	layoutsFile := flag.String("layouts", "", "JSON file with screen layouts, selected with the number keys")
+	startLayout := flag.Int("layout", 3, "number of the layout to start with")
+	replayDir := flag.String("replay-dir", "./replays", "directory for input recordings (F9)")
+	playFile := flag.String("play", "", "replay to play at start")
+	demoFile := flag.String("demo", "./resources/demos/title.json", "replay shown from the title menu")
+	players := flag.Int("players", 1, "players sharing the screen, each after the first needs a gamepad")
+	hostAddr := flag.String("host", "", "address to host a game on, such as :7777")
+	joinAddr := flag.String("join", "", "address of a hosted game to join")
+	saveDir := flag.String("save-dir", "./saves", "directory for saved games")
+	settingsFile := flag.String("settings", "settings.json", "JSON file with settings")
+	width := flag.Int("width", 0, "width to render at, 0 follows the window")
+	height := flag.Int("height", 0, "height to render at, 0 follows the window")
+	scaling := flag.String("scaling", "", "how to scale to the window, integer or filtered")
+	aspect := flag.Float64("aspect", 0, "width to height ratio to keep, 0 follows the window")
+	fullscreen := flag.Bool("fullscreen", false, "start in fullscreen, F11 toggles it")
+	flag.Parse()
+
+	s, err := settings.Load(*settingsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// flags given on the command line override the settings file
+	layoutGiven := false
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "layout":
+			layoutGiven = true
+		case "width":
+			s.Display.Width = *width
+		case "height":
+			s.Display.Height = *height
+		case "scaling":
+			s.Display.Scaling = display.Scaling(*scaling)
+		case "aspect":
+			s.Display.Aspect = *aspect
+		case "fullscreen":
+			s.Display.Fullscreen = *fullscreen
+		}
+	})
+
 	// initialize some ebiten options
 	ebiten.SetWindowSize(1600, 800)
-	ebiten.SetCursorMode(ebiten.CursorModeCaptured)
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
-	// ebiten.SetFullscreen(true)
+	ebiten.SetFullscreen(s.Display.Fullscreen)
 	ebiten.SetScreenClearedEveryFrame(false)
 
 	// create some map
-	mab := makeStandardMap()
-	mab[1][7] = 2
-	mab[2][7] = 2
-	mab[3][7] = 2
-	mab[4][7] = 2
-	mab[4][8] = 2
-	mab[4][9] = 2
-	mab[4][10] = 2
-	mab[4][11] = 2
-	mab[4][12] = 2
-	mab[4][13] = 2
-	mab[4][14] = 2
-	mab[13][14] = 2
-	mab[14][14] = 2
-	mab[15][14] = 2
-	mab[16][14] = 2
-	mab[16][13] = 2
-	mab[16][12] = 2
-	mab[16][11] = 2
+	mab := maps.Demo(BLOCKS_X, BLOCKS_Y)
+
+	// the corner behind the first wall is a dark, foggy cave
+	cave := lighting.Default()
+	cave.Ambient = 0.5
+	cave.FaceBrightness = [4]float64{1, 1, 0.7, 0.7}
+	cave.FogColor = color.RGBA{10, 10, 20, 255}
+	cave.FogCurve = lighting.FOG_EXP
+	cave.FogDensity = 0.004
+
+	// the open area to the right has no ceiling
+	outdoor := make([][]bool, BLOCKS_Y)
+	for y := range outdoor {
+		outdoor[y] = make([]bool, BLOCKS_X)
+		for x := range outdoor[y] {
+			outdoor[y][x] = x >= 15 && y <= 12
+		}
+	}
+
+	// a torch in the cave and a lamp in the open
+	lights := lighting.NewLights(BLOCK_SIZE, mab)
+	lights.Add(&lighting.PointLight{
+		Coord:     raycasting.Coordinate{X: 3.5 * BLOCK_SIZE, Y: 2.5 * BLOCK_SIZE},
+		Color:     color.RGBA{255, 160, 60, 255},
+		Radius:    6 * BLOCK_SIZE,
+		Intensity: 1.5,
+		Flicker:   0.4,
+	})
+	lights.Add(&lighting.PointLight{
+		Coord:     raycasting.Coordinate{X: 20.5 * BLOCK_SIZE, Y: 10.5 * BLOCK_SIZE},
+		Color:     color.RGBA{120, 160, 255, 255},
+		Radius:    8 * BLOCK_SIZE,
+		Intensity: 1,
+	})
 
 	// create the game struct
-	game := &Game{
-		player: &player.Player{
-			Coord: &raycasting.Coordinate{
-				X: WORLD_WIDTH / 2.,
-				Y: WORLD_HEIGHT / 2.,
-			},
-			Angle: 0,
-			Speed: 10.,
+	p := &player.Player{
+		Coord: &raycasting.Coordinate{
+			X: WORLD_WIDTH / 2.,
+			Y: WORLD_HEIGHT / 2.,
 		},
-		mab:           mab,
-		represntation: 2,
+		Angle:     0,
+		Speed:     10.,
+		Health:    100,
+		MaxHealth: 100,
+	}
+	enemyTypes, err := enemy.LoadTypes(ENEMY_TYPES)
+	if err != nil {
+		log.Fatal(err)
+	}
+	weaponTypes, err := weapon.LoadTypes(WEAPON_TYPES)
+	if err != nil {
+		log.Fatal(err)
+	}
+	projectileTypes, err := projectile.LoadTypes(PROJECTILE_TYPES)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := checkProjectiles(weaponTypes, enemyTypes, projectileTypes); err != nil {
+		log.Fatal(err)
+	}
+	// a bad LUT in the settings should not keep the game from starting
+	effects, err := postfx.New(s.Effects)
+	if err != nil {
+		log.Printf("could not use the color grading LUT, using none: %v", err)
+	}
+	world := sim.New(p, mab, BLOCK_SIZE, 1)
+	world.Lights = lights
+	game := &Game{
+		world:         world,
+		minimap:       true,
+		minimapZoom:   1,
+		explored:      automap.New(mab),
 		updateRenders: true,
+		lightingZones: []lighting.Zone{
+			{MinX: 1, MinY: 1, MaxX: 6, MaxY: 3, Config: cave},
+		},
+		sky:             rendering.LoadSky(rendering.SKY),
+		outdoor:         outdoor,
+		settings:        s,
+		postfx:          effects,
+		settingsFile:    *settingsFile,
+		mapID:           replay.DEMO_MAP,
+		replayDir:       *replayDir,
+		saveDir:         *saveDir,
+		enemyTypes:      enemyTypes,
+		weaponTypes:     weaponTypes,
+		projectileTypes: projectileTypes,
+	}
+	game.arm(world)
+	game.spawnEnemies()
+
+	captureFormat := capture.PNG_SEQUENCE
+	if *captureGIF {
+		captureFormat = capture.GIF
+	}
+	game.hud = newHUD(s.HUD, p)
+	addWeaponHUD(game.hud, world.Arsenal)
+	game.input = live.New(&game.settings.Controls)
+	game.source = game.input
+
+	// the console uses the font of the HUD
+	c := console.New()
+	game.registerCommands(c)
+	game.registerSaveCommands(c)
+	game.registerNetCommands(c)
+	game.registerSplitCommands(c)
+	game.registerEnemyCommands(c)
+	game.postfx.RegisterCommands(c)
+	game.console = view.New(c, game.hud.Face)
+	game.inspector = &hud.Text{
+		Value: func() string { return game.inspected },
+		Color: color.RGBA{255, 255, 0, 255},
+	}
+	game.capturer = capture.New(*captureDir, captureFormat)
+
+	game.layouts = layout.Defaults()
+	if *layoutsFile != "" {
+		layouts, err := layout.Load(*layoutsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		game.layouts = layouts
+	}
+	game.layoutIdx = min(max(*startLayout-1, 0), len(game.layouts)-1)
+	for i, l := range game.layouts {
+		if !layoutGiven && l.Name == s.View.Layout {
+			game.layoutIdx = i
+		}
+	}
+	game.demoFile = *demoFile
+	if *players > 1 {
+		if err := game.setPlayers(*players); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *hostAddr != "" {
+		if err := game.host(*hostAddr); err != nil {
+			log.Fatal(err)
+		}
+	} else if *joinAddr != "" {
+		if err := game.join(*joinAddr); err != nil {
+			log.Fatal(err)
+		}
+	}
+	game.scenes = scene.NewManager(game.newTitleMenu())
+	if *playFile != "" {
+		r, err := replay.Load(*playFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := game.startPlayback(r, nil); err != nil {
+			log.Fatal(err)
+		}
+		game.scenes.Replace(&playScene{game})
 	}
 
 	// run the main loop
-	if err := ebiten.RunGame(game); err != nil {
+	// quitting returns ebiten.Termination from Update, which ends RunGame without an error
+	err = ebiten.RunGame(game)
+	game.disconnect()
+	if game.capturer.Recording() {
+		game.toggleCapture()
+	}
+	if game.recording != nil {
+		game.toggleRecording()
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,115 @@
+package maps
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// MAX_WALL_TYPE is the last wall type the renderers have a texture for, from 1
+const MAX_WALL_TYPE = 2
+
+// Standard is an empty map of the given size in blocks, surrounded by walls
+func Standard(blocksX, blocksY int) [][]raycasting.WallType {
+	m := make([][]raycasting.WallType, blocksY)
+	for i := range m {
+		m[i] = make([]raycasting.WallType, blocksX)
+	}
+	for y := 0; y < blocksY; y++ {
+		for x := 0; x < blocksX; x++ {
+			if x == 0 || x == blocksX-1 || y == 0 || y == blocksY-1 {
+				m[y][x] = 1
+			}
+		}
+	}
+
+	return m
+}
+
+// Demo is the standard map with a couple of walls placed in it
+func Demo(blocksX, blocksY int) [][]raycasting.WallType {
+	mab := Standard(blocksX, blocksY)
+	mab[1][7] = 2
+	mab[2][7] = 2
+	mab[3][7] = 2
+	mab[4][7] = 2
+	mab[4][8] = 2
+	mab[4][9] = 2
+	mab[4][10] = 2
+	mab[4][11] = 2
+	mab[4][12] = 2
+	mab[4][13] = 2
+	mab[4][14] = 2
+	mab[13][14] = 2
+	mab[14][14] = 2
+	mab[15][14] = 2
+	mab[16][14] = 2
+	mab[16][13] = 2
+	mab[16][12] = 2
+	mab[16][11] = 2
+	return mab
+}
+
+// Load reads a map from a CSV file, with a wall type for each cell and a row per line.
+// The map should be surrounded by walls, have a cell to stand in, and only
+// wall types with a texture.
+func Load(path string) ([][]raycasting.WallType, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New(path + ": map is empty")
+	}
+
+	m := make([][]raycasting.WallType, len(rows))
+	for y, row := range rows {
+		m[y] = make([]raycasting.WallType, len(row))
+		for x, e := range row {
+			n, err := strconv.Atoi(strings.TrimSpace(e))
+			if err != nil {
+				return nil, fmt.Errorf("%v: row %d: %w", path, y+1, err)
+			}
+			if n < 0 || n > MAX_WALL_TYPE {
+				return nil, fmt.Errorf("%v: row %d: unknown wall type %d", path, y+1, n)
+			}
+			m[y][x] = raycasting.WallType(n)
+		}
+	}
+	if err := check(m); err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return m, nil
+}
+
+// check is whether the rays and players stay inside m, as open cells on the
+// border would let them out, and there is somewhere to stand
+func check(m [][]raycasting.WallType) error {
+	open := false
+	for y, row := range m {
+		for x, w := range row {
+			if w != 0 {
+				continue
+			}
+			if y == 0 || y == len(m)-1 || x == 0 || x == len(row)-1 {
+				return fmt.Errorf("row %d: open cell %d on the border", y+1, x+1)
+			}
+			open = true
+		}
+	}
+	if !open {
+		return errors.New("map has no open cell")
+	}
+	return nil
+}
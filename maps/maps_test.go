@@ -0,0 +1,67 @@
+package maps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("Cells", func(t *testing.T) {
+		m, err := Load(write("ok.csv", "1,1,1\n1, 0,2\n1,1,1\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m) != 3 || len(m[0]) != 3 || m[1][1] != 0 || m[1][2] != 2 {
+			t.Fatalf("Map should have the cells of the file, got: %v", m)
+		}
+	})
+
+	t.Run("Rows of different length", func(t *testing.T) {
+		if _, err := Load(write("ragged.csv", "1,1,1\n1,1\n")); err == nil {
+			t.Fatalf("Rows of different length should fail")
+		}
+	})
+
+	t.Run("Not a number", func(t *testing.T) {
+		if _, err := Load(write("nan.csv", "1,x\n")); err == nil {
+			t.Fatalf("A cell that is not a number should fail")
+		}
+	})
+
+	t.Run("Unknown wall type", func(t *testing.T) {
+		if _, err := Load(write("type.csv", "1,1,1\n1,0,9\n1,1,1\n")); err == nil {
+			t.Fatalf("A wall type without a texture should fail")
+		}
+		if _, err := Load(write("negative.csv", "1,1,1\n1,0,-1\n1,1,1\n")); err == nil {
+			t.Fatalf("A negative wall type should fail")
+		}
+	})
+
+	t.Run("Open border", func(t *testing.T) {
+		if _, err := Load(write("border.csv", "1,1,1\n1,0,0\n1,1,1\n")); err == nil {
+			t.Fatalf("An open cell on the border should fail")
+		}
+	})
+
+	t.Run("No open cell", func(t *testing.T) {
+		if _, err := Load(write("closed.csv", "1,1,1\n1,2,1\n1,1,1\n")); err == nil {
+			t.Fatalf("A map without an open cell should fail")
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		if _, err := Load(write("empty.csv", "")); err == nil {
+			t.Fatalf("An empty file should fail")
+		}
+	})
+}
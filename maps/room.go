@@ -0,0 +1,26 @@
+package maps
+
+import "github.com/hvassaa/gaster/raycasting"
+
+// The room is a small standard map for tests to move things in, with blocks of ROOM_BLOCK_SIZE
+const (
+	ROOM_SIZE       = 20
+	ROOM_BLOCK_SIZE = 40.
+	// ROOM_EAST_WALL is the first block of the east wall
+	ROOM_EAST_WALL = ROOM_SIZE - 1
+)
+
+// Room is an empty standard map of ROOM_SIZE blocks both ways
+func Room() [][]raycasting.WallType {
+	return Standard(ROOM_SIZE, ROOM_SIZE)
+}
+
+// WithWall is the room with a wall across the middle, from the north wall
+// down to row 14, so the way around it is at the bottom
+func WithWall() [][]raycasting.WallType {
+	m := Room()
+	for y := 0; y < 15; y++ {
+		m[y][ROOM_SIZE/2] = 1
+	}
+	return m
+}
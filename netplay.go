@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"log"
+	"net"
+	"strconv"
+
+	"github.com/hvassaa/gaster/console"
+	"github.com/hvassaa/gaster/netplay"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/rendering"
+)
+
+// DEFAULT_PORT is used for hosting and joining when no port is given
+const DEFAULT_PORT = 7777
+
+// PLAYER_COLORS are the colors of the other players, by ID
+var PLAYER_COLORS = []color.RGBA{
+	{230, 80, 80, 255},
+	{80, 160, 230, 255},
+	{240, 200, 60, 255},
+	{120, 210, 110, 255},
+	{200, 110, 220, 255},
+}
+
+// withPort adds the default port to addr if it has none
+func withPort(addr string) string {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return net.JoinHostPort(addr, strconv.Itoa(DEFAULT_PORT))
+	}
+	return addr
+}
+
+// host starts a server with the current map in the background, and joins it
+func (g *Game) host(addr string) error {
+	if g.client != nil {
+		return errors.New("already connected")
+	}
+	s, err := netplay.Listen(withPort(addr), g.world.Map, BLOCK_SIZE, *g.world.Player.Coord, g.world.Player.Speed)
+	if err != nil {
+		return err
+	}
+	g.server = s
+	g.serverStop = make(chan struct{})
+	go s.Run(g.serverStop)
+	log.Printf("hosting on %v", s.Addr())
+	if err := g.join(net.JoinHostPort("127.0.0.1", strconv.Itoa(s.Addr().Port))); err != nil {
+		g.disconnect()
+		return err
+	}
+	return nil
+}
+
+// join connects to a server, and plays on its map
+func (g *Game) join(addr string) error {
+	if g.client != nil {
+		return errors.New("already connected")
+	}
+	if g.playback != nil {
+		return errors.New("cannot join while a replay is playing")
+	}
+	if len(g.locals) > 0 {
+		return errors.New("cannot join while sharing the screen")
+	}
+	c, err := netplay.Dial(withPort(addr), g.world.Player)
+	if err != nil {
+		return err
+	}
+	g.client = c
+	g.world.Noclip = false
+	g.setMap("net:"+addr, c.Map)
+	log.Printf("joined %v as player %d", addr, c.ID)
+	return nil
+}
+
+// pollServer uses the newest state from the server, and leaves it if it stopped answering
+func (g *Game) pollServer() {
+	if g.client == nil {
+		return
+	}
+	if err := g.client.Poll(); err != nil {
+		log.Printf("disconnected: %v", err)
+		g.disconnect()
+	}
+}
+
+// disconnect leaves the server, and stops it if it was hosted here
+func (g *Game) disconnect() {
+	if g.client != nil {
+		g.client.Close()
+		g.client = nil
+	}
+	if g.server != nil {
+		close(g.serverStop)
+		g.server.Close()
+		g.server = nil
+	}
+}
+
+// sprites are the other players
+func (g *Game) sprites() []rendering.Sprite {
+	if g.client == nil {
+		return nil
+	}
+	res := make([]rendering.Sprite, len(g.client.Others))
+	for i, o := range g.client.Others {
+		res[i] = rendering.Sprite{
+			Coord: raycasting.Coordinate{X: o.X, Y: o.Y},
+			Size:  0.8,
+			Color: PLAYER_COLORS[(o.ID-1)%len(PLAYER_COLORS)],
+		}
+	}
+	return res
+}
+
+func (g *Game) registerNetCommands(c *console.Console) {
+	c.Register(console.Command{
+		Name:  "host",
+		Usage: "[address]",
+		Help:  fmt.Sprintf("hosts a game on the current map, on port %d if none is given", DEFAULT_PORT),
+		Run: func(args []string) (string, error) {
+			addr := ""
+			if len(args) > 0 {
+				addr = args[0]
+			}
+			return "", g.host(addr)
+		},
+	})
+	c.Register(console.Command{
+		Name:  "join",
+		Usage: "<address>",
+		Help:  "joins a hosted game",
+		Run: func(args []string) (string, error) {
+			if len(args) != 1 {
+				return "", errors.New("expected an address")
+			}
+			return "", g.join(args[0])
+		},
+	})
+	c.Register(console.Command{
+		Name: "disconnect",
+		Help: "leaves the game, or stops hosting it",
+		Run: func(args []string) (string, error) {
+			if g.client == nil {
+				return "", errors.New("not connected")
+			}
+			g.disconnect()
+			return "", nil
+		},
+	})
+}
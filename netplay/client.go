@@ -0,0 +1,247 @@
+package netplay
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/hvassaa/gaster/input"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/sim"
+)
+
+// JOIN_TRIES is how many times HELLO is sent without an answer, JOIN_WAIT apart, before giving up
+const (
+	JOIN_TRIES = 10
+	JOIN_WAIT  = 300 * time.Millisecond
+)
+
+type Client struct {
+	// ID is the player of this client
+	ID int
+	// Map and BlockSize are those of the server
+	Map       [][]raycasting.WallType
+	BlockSize float64
+	// Others are the other players, as last sent by the server
+	Others []State
+	// Tick is the server tick of the last state
+	Tick int
+	// Timeout is how long the server can be silent before Poll gives up
+	Timeout time.Duration
+
+	conn *net.UDPConn
+	// world steps the inputs the server has not acknowledged again, after
+	// the player is put where the server says
+	world    *sim.World
+	seq      int
+	pending  []Input
+	messages chan Message
+	// heard is when the last state arrived, and sent is whether Send was called since KeepAlive
+	heard time.Time
+	sent  bool
+}
+
+// ErrTimeout is returned by Poll when the server has been silent for longer than Timeout
+var ErrTimeout = errors.New("the server stopped answering")
+
+// Dial joins the server at addr, and moves p to where the server put it.
+// The player should then be moved by the same frames that are given to Send.
+func Dial(addr string, p *player.Player) (*Client, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, Timeout: TIMEOUT, messages: make(chan Message, 256)}
+	welcome, err := c.join()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if len(welcome.Players) != 1 || len(welcome.Map) == 0 {
+		conn.Close()
+		return nil, errors.New("bad welcome from the server")
+	}
+	c.ID = welcome.ID
+	c.Map = welcome.Map
+	c.BlockSize = welcome.BlockSize
+	p.Speed = welcome.Speed
+	c.world = sim.New(p, c.Map, c.BlockSize, 1)
+	c.place(welcome.Players[0])
+	c.heard = time.Now()
+	go c.read()
+	return c, nil
+}
+
+// join says hello until the server has welcomed with every row of the map.
+// HELLO says which row is missing first, so the server only sends the rest,
+// and a try that got more rows does not count.
+func (c *Client) join() (Message, error) {
+	buf := make([]byte, MAX_PACKET)
+	var welcome Message
+	got, missing := 0, 0
+	for tries := 0; tries < JOIN_TRIES; {
+		if err := c.send(Message{Kind: HELLO, Version: VERSION, Row: missing}); err != nil {
+			return Message{}, err
+		}
+		before := got
+		c.conn.SetReadDeadline(time.Now().Add(JOIN_WAIT))
+		for {
+			n, err := c.conn.Read(buf)
+			if err != nil {
+				break
+			}
+			m, err := decode(buf[:n])
+			if err != nil || m.Kind != WELCOME || m.Rows <= 0 || m.Row < 0 || m.Row+len(m.Map) > m.Rows {
+				continue
+			}
+			if len(welcome.Map) != m.Rows {
+				welcome, welcome.Map, got, missing = m, make([][]raycasting.WallType, m.Rows), 0, 0
+			}
+			for i, row := range m.Map {
+				if welcome.Map[m.Row+i] == nil {
+					welcome.Map[m.Row+i] = row
+					got++
+				}
+			}
+			for missing < len(welcome.Map) && welcome.Map[missing] != nil {
+				missing++
+			}
+			if got == len(welcome.Map) {
+				c.conn.SetReadDeadline(time.Time{})
+				return welcome, nil
+			}
+		}
+		if got == before {
+			tries++
+		}
+	}
+	return Message{}, fmt.Errorf("no answer from %v", c.conn.RemoteAddr())
+}
+
+// read passes messages to Poll until the connection is closed
+func (c *Client) read() {
+	buf := make([]byte, MAX_PACKET)
+	for {
+		n, err := c.conn.Read(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		m, err := decode(buf[:n])
+		if err != nil || m.Kind != STATE {
+			continue
+		}
+		select {
+		case c.messages <- m:
+		default:
+		}
+	}
+}
+
+func (c *Client) send(m Message) error {
+	data, err := encode(m)
+	if err != nil {
+		return err
+	}
+	_, err = c.conn.Write(data)
+	return err
+}
+
+// Send sends the actions of a tick, which the player has already been moved by.
+// The latest inputs the server has not acknowledged are sent along.
+func (c *Client) Send(f input.Frame) error {
+	c.seq++
+	c.pending = append(c.pending, Input{Seq: c.seq, Frame: f})
+	if len(c.pending) > MAX_PENDING {
+		c.pending = c.pending[len(c.pending)-MAX_PENDING:]
+	}
+	c.sent = true
+	return c.resend()
+}
+
+// KeepAlive resends the inputs the server has not acknowledged, if Send was
+// not called since the last KeepAlive, so the server knows the client is
+// still there while the game is paused.
+func (c *Client) KeepAlive() error {
+	if c.sent {
+		c.sent = false
+		return nil
+	}
+	return c.resend()
+}
+
+func (c *Client) resend() error {
+	return c.send(Message{Kind: INPUT, Inputs: c.pending[max(len(c.pending)-RESEND, 0):]})
+}
+
+// Poll uses the newest state from the server, if one arrived since the last call.
+// The player is put where the server has it, and the inputs the server has
+// not used yet are stepped again from there. ErrTimeout is returned if no
+// state arrived for Timeout.
+func (c *Client) Poll() error {
+	var latest *Message
+drain:
+	for {
+		select {
+		case m := <-c.messages:
+			if m.Tick > c.Tick && (latest == nil || m.Tick > latest.Tick) {
+				latest = &m
+			}
+		default:
+			break drain
+		}
+	}
+	if latest == nil {
+		if time.Since(c.heard) > c.Timeout {
+			return ErrTimeout
+		}
+		return nil
+	}
+	c.heard = time.Now()
+	c.Tick = latest.Tick
+
+	others := c.Others[:0]
+	for _, s := range latest.Players {
+		if s.ID == c.ID {
+			c.place(s)
+		} else {
+			others = append(others, s)
+		}
+	}
+	c.Others = others
+
+	acked := 0
+	for acked < len(c.pending) && c.pending[acked].Seq <= latest.Ack {
+		acked++
+	}
+	c.pending = c.pending[acked:]
+	for _, in := range c.pending {
+		c.world.Step(in.Frame)
+	}
+	return nil
+}
+
+// place puts the player where s says
+func (c *Client) place(s State) {
+	p := c.world.Player
+	p.Coord.X, p.Coord.Y, p.Angle, p.HozAngle = s.X, s.Y, s.Angle, s.HozAngle
+}
+
+// Pending is how many inputs the server has not acknowledged
+func (c *Client) Pending() int {
+	return len(c.pending)
+}
+
+// Close tells the server the client leaves
+func (c *Client) Close() error {
+	c.send(Message{Kind: BYE})
+	return c.conn.Close()
+}
@@ -0,0 +1,217 @@
+package netplay
+
+import (
+	"math"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hvassaa/gaster/input"
+	"github.com/hvassaa/gaster/maps"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+const blockSize = maps.ROOM_BLOCK_SIZE
+
+func newServer(t *testing.T) *Server {
+	t.Helper()
+	s, err := Listen("127.0.0.1:0", maps.Room(), blockSize, raycasting.Coordinate{X: 410, Y: 410}, 5)
+	if err != nil {
+		t.Fatalf("Server should listen, got: %v", err)
+	}
+	stop := make(chan struct{})
+	go s.Run(stop)
+	t.Cleanup(func() {
+		close(stop)
+		s.Close()
+	})
+	return s
+}
+
+func dial(t *testing.T, s *Server) (*Client, *player.Player) {
+	t.Helper()
+	p := &player.Player{Coord: &raycasting.Coordinate{}}
+	c, err := Dial(s.Addr().String(), p)
+	if err != nil {
+		t.Fatalf("Client should join, got: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, p
+}
+
+// until polls the clients until ok, or fails after a second
+func until(t *testing.T, ok func() bool, clients ...*Client) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !ok() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Clients should agree with the server within a second, got %d pending inputs", clients[0].Pending())
+		}
+		time.Sleep(time.Millisecond)
+		for _, c := range clients {
+			c.Poll()
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	s := newServer(t)
+	a, pa := dial(t, s)
+	b, _ := dial(t, s)
+	if a.ID == b.ID {
+		t.Fatalf("Clients should get different IDs, got: %v and %v", a.ID, b.ID)
+	}
+	if pa.Coord.X != 410 || pa.Coord.Y != 410 || pa.Speed != 5 {
+		t.Fatalf("Player should be at the spawn with the speed of the server, got: %v %v", *pa.Coord, pa.Speed)
+	}
+	if len(a.Map) != maps.ROOM_SIZE || a.BlockSize != blockSize {
+		t.Fatalf("Client should get the map of the server, got: %d rows of %v", len(a.Map), a.BlockSize)
+	}
+	until(t, func() bool { return len(a.Others) == 1 && a.Others[0].ID == b.ID }, a, b)
+}
+
+func TestJoinLargeMap(t *testing.T) {
+	// far too large for one packet
+	m := maps.Standard(300, 300)
+	s, err := Listen("127.0.0.1:0", m, blockSize, raycasting.Coordinate{X: 410, Y: 410}, 5)
+	if err != nil {
+		t.Fatalf("Server should listen, got: %v", err)
+	}
+	stop := make(chan struct{})
+	go s.Run(stop)
+	t.Cleanup(func() {
+		close(stop)
+		s.Close()
+	})
+	a, _ := dial(t, s)
+	if !reflect.DeepEqual(a.Map, m) {
+		t.Fatalf("Client should get the whole map, got %d rows", len(a.Map))
+	}
+}
+
+func TestPrediction(t *testing.T) {
+	t.Run("Predicted movement matches the server", func(t *testing.T) {
+		s := newServer(t)
+		a, pa := dial(t, s)
+		b, _ := dial(t, s)
+
+		// the client moves its player right away, like the game does
+		walk := input.Frame{Move: 1}
+		for i := 0; i < 20; i++ {
+			pa.Move(walk.Move)
+			a.Send(walk)
+		}
+		if math.Abs(pa.Coord.X-510) > 1e-9 {
+			t.Fatalf("Player should be predicted 100 east, got: %v", *pa.Coord)
+		}
+		until(t, func() bool { return a.Pending() == 0 }, a)
+		if math.Abs(pa.Coord.X-510) > 1e-9 || pa.Coord.Y != 410 {
+			t.Fatalf("Player should stay where it was predicted, got: %v", *pa.Coord)
+		}
+		until(t, func() bool { return len(b.Others) == 1 && b.Others[0].X == pa.Coord.X }, b)
+	})
+
+	t.Run("Wrong prediction is corrected", func(t *testing.T) {
+		s := newServer(t)
+		a, pa := dial(t, s)
+		a.Send(input.Frame{Move: 1})
+		// the server has no idea about this
+		pa.Coord.X = 100
+		until(t, func() bool { return a.Pending() == 0 }, a)
+		if math.Abs(pa.Coord.X-415) > 1e-9 {
+			t.Fatalf("Player should be where the server has it, got: %v", *pa.Coord)
+		}
+	})
+
+	t.Run("Oversized input is limited", func(t *testing.T) {
+		s := newServer(t)
+		a, pa := dial(t, s)
+		a.Send(input.Frame{Move: 100, Strafe: -50, Turn: -1e9})
+		until(t, func() bool { return a.Pending() == 0 }, a)
+		if d := pa.Coord.DistanceTo(raycasting.Coordinate{X: 410, Y: 410}); d > 10+1e-9 {
+			t.Fatalf("Player should move at most a step each way, got: %v", *pa.Coord)
+		}
+	})
+
+	t.Run("Walls are respected", func(t *testing.T) {
+		s := newServer(t)
+		a, pa := dial(t, s)
+		// sent at the tick rate like the game does, as the server uses one input a tick,
+		// and enough to walk from the spawn into the wall
+		for i := 0; i < 80; i++ {
+			a.Send(input.Frame{Move: 1})
+			time.Sleep(time.Second / TICK_RATE)
+		}
+		until(t, func() bool { return a.Pending() == 0 }, a)
+		if pa.Coord.X >= maps.ROOM_EAST_WALL*blockSize {
+			t.Fatalf("Player should stop before the east wall, got: %v", *pa.Coord)
+		}
+	})
+}
+
+func TestOneInputPerTick(t *testing.T) {
+	s, err := Listen("127.0.0.1:0", maps.Room(), blockSize, raycasting.Coordinate{X: 410, Y: 410}, 5)
+	if err != nil {
+		t.Fatalf("Server should listen, got: %v", err)
+	}
+	defer s.Close()
+	// stepped by hand, so no client is needed
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9}
+	s.handle(packet{Message{Kind: HELLO, Version: VERSION}, addr}, time.Now())
+	var inputs []Input
+	for i := 1; i <= 10; i++ {
+		inputs = append(inputs, Input{Seq: i, Frame: input.Frame{Move: 1}})
+	}
+	s.handle(packet{Message{Kind: INPUT, Inputs: inputs}, addr}, time.Now())
+
+	s.Step()
+	if x := s.Players()[0].X; math.Abs(x-415) > 1e-9 {
+		t.Fatalf("Player should move one step in a tick, got: %v", x)
+	}
+	for i := 0; i < 9; i++ {
+		s.Step()
+	}
+	if x := s.Players()[0].X; math.Abs(x-460) > 1e-9 {
+		t.Fatalf("Player should use the other inputs in later ticks, got: %v", x)
+	}
+}
+
+func TestLeave(t *testing.T) {
+	s := newServer(t)
+	a, _ := dial(t, s)
+	b, _ := dial(t, s)
+	until(t, func() bool { return len(a.Others) == 1 }, a)
+	b.Close()
+	until(t, func() bool { return len(a.Others) == 0 }, a)
+}
+
+func TestServerGone(t *testing.T) {
+	s := newServer(t)
+	a, _ := dial(t, s)
+	a.Timeout = 50 * time.Millisecond
+	s.Close()
+
+	t.Run("Pending inputs are capped", func(t *testing.T) {
+		for i := 0; i < MAX_PENDING+10; i++ {
+			a.Send(input.Frame{Move: 1})
+		}
+		if a.Pending() != MAX_PENDING {
+			t.Fatalf("Client should keep %d pending inputs, got: %d", MAX_PENDING, a.Pending())
+		}
+	})
+
+	t.Run("Poll times out", func(t *testing.T) {
+		deadline := time.Now().Add(time.Second)
+		for a.Poll() == nil {
+			if time.Now().After(deadline) {
+				t.Fatalf("Poll should time out when the server is gone")
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if err := a.Poll(); err != ErrTimeout {
+			t.Fatalf("Poll should return ErrTimeout, got: %v", err)
+		}
+	})
+}
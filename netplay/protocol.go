@@ -0,0 +1,114 @@
+// Package netplay lets several players walk the same map over UDP. The server
+// is authoritative over where every player is. Clients move their own player
+// right away, and correct it when the server answers.
+package netplay
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hvassaa/gaster/input"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+const (
+	// VERSION is sent when joining, and servers ignore clients of other versions
+	VERSION = 2
+	// TICK_RATE is how many times a second the server steps, the same as the game
+	TICK_RATE = 60
+	// RESEND is how many of the inputs the server has not acknowledged are sent
+	// with every new input, so a lost packet does not lose any movement
+	RESEND = 16
+	// TIMEOUT is how long a client can be silent before the server drops it,
+	// and how long the server can be silent before a client gives up
+	TIMEOUT = 5 * time.Second
+	// MAX_PENDING is how many inputs a client keeps for the server to acknowledge,
+	// after which the oldest are dropped
+	MAX_PENDING = 2 * TICK_RATE
+	// MAX_TURN and MAX_LOOK are how far a client can turn and look in a tick,
+	// enough to face any way
+	MAX_TURN = raycasting.PI
+	MAX_LOOK = 720.
+	// MAX_PACKET is the largest UDP payload
+	MAX_PACKET = 65507
+	// MAP_CHUNK is about how many bytes of the map a WELCOME carries, as
+	// large maps do not fit in one packet
+	MAP_CHUNK = 8 * 1024
+)
+
+type Kind string
+
+const (
+	// HELLO asks to join, and is answered with a WELCOME for every chunk of the map
+	HELLO   Kind = "hello"
+	WELCOME Kind = "welcome"
+	// INPUT is sent by clients every tick, even without inputs to keep the
+	// server from dropping them, and STATE by the server
+	INPUT Kind = "input"
+	STATE Kind = "state"
+	// BYE is sent by clients leaving
+	BYE Kind = "bye"
+)
+
+// Input is the actions of one tick of a client, numbered from 1
+type Input struct {
+	Seq   int
+	Frame input.Frame
+}
+
+// State is where a player is
+type State struct {
+	ID                    int
+	X, Y, Angle, HozAngle float64
+}
+
+// Message is everything sent either way, with the fields used by its kind
+type Message struct {
+	Kind    Kind
+	Version int `json:",omitempty"`
+	// ID is the player of the client, sent in WELCOME and STATE
+	ID int `json:",omitempty"`
+	// Map, BlockSize and Speed are sent in WELCOME, so clients walk like the server.
+	// Map is the rows from Row of the Rows rows of the whole map. HELLO has
+	// the first row the client is missing as Row.
+	Map       [][]raycasting.WallType `json:",omitempty"`
+	Row       int                     `json:",omitempty"`
+	Rows      int                     `json:",omitempty"`
+	BlockSize float64                 `json:",omitempty"`
+	Speed     float64                 `json:",omitempty"`
+	Inputs    []Input                 `json:",omitempty"`
+	// Ack is the last input of the client the server has used
+	Ack     int     `json:",omitempty"`
+	Tick    int     `json:",omitempty"`
+	Players []State `json:",omitempty"`
+}
+
+func encode(m Message) ([]byte, error) {
+	data, err := json.Marshal(m)
+	if err == nil && len(data) > MAX_PACKET {
+		return nil, fmt.Errorf("%v message of %d bytes does not fit in a packet", m.Kind, len(data))
+	}
+	return data, err
+}
+
+// chunks splits m into rows of about MAP_CHUNK bytes, and gives where each starts
+func chunks(m [][]raycasting.WallType) (starts []int) {
+	size := 0
+	for i, row := range m {
+		// a wall type is mostly a digit and a comma
+		rowSize := 2*len(row) + 2
+		if i == 0 || size+rowSize > MAP_CHUNK {
+			starts = append(starts, i)
+			size = 0
+		}
+		size += rowSize
+	}
+	return starts
+}
+
+func decode(data []byte) (Message, error) {
+	var m Message
+	err := json.Unmarshal(data, &m)
+	return m, err
+}
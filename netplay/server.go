@@ -0,0 +1,266 @@
+package netplay
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/hvassaa/gaster/input"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/sim"
+)
+
+// packet is a message and who sent it
+type packet struct {
+	Message
+	addr *net.UDPAddr
+}
+
+// remote is a client of the server, with a world of its own to step its player in
+type remote struct {
+	id    int
+	addr  *net.UDPAddr
+	world *sim.World
+	// ack is the last input used, and inputs are the ones received but not used yet,
+	// which are used one a tick
+	ack    int
+	inputs []Input
+	seen   time.Time
+}
+
+type Server struct {
+	Map       [][]raycasting.WallType
+	BlockSize float64
+	// Spawn is where players join, and Speed how fast they walk
+	Spawn raycasting.Coordinate
+	Speed float64
+	Tick  int
+
+	conn    *net.UDPConn
+	packets chan packet
+	clients map[string]*remote
+	nextID  int
+	// closed is set when Step finds the connection closed
+	closed bool
+}
+
+// Listen starts a server on addr, such as ":7777" or "127.0.0.1:0" for any free port
+func Listen(addr string, m [][]raycasting.WallType, blockSize float64, spawn raycasting.Coordinate, speed float64) (*Server, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Map:       m,
+		BlockSize: blockSize,
+		Spawn:     spawn,
+		Speed:     speed,
+		conn:      conn,
+		packets:   make(chan packet, 1024),
+		clients:   map[string]*remote{},
+		nextID:    1,
+	}
+	go s.read()
+	return s, nil
+}
+
+// Addr is the address the server listens on
+func (s *Server) Addr() *net.UDPAddr {
+	return s.conn.LocalAddr().(*net.UDPAddr)
+}
+
+// read passes packets to Step until the connection is closed
+func (s *Server) read() {
+	buf := make([]byte, MAX_PACKET)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if errors.Is(err, net.ErrClosed) {
+			close(s.packets)
+			return
+		}
+		if err != nil {
+			continue
+		}
+		m, err := decode(buf[:n])
+		if err != nil {
+			continue
+		}
+		select {
+		case s.packets <- packet{m, addr}:
+		default:
+			// Step is not keeping up, and the clients resend inputs anyway
+		}
+	}
+}
+
+// Players is where every player is, ordered by ID
+func (s *Server) Players() []State {
+	res := make([]State, 0, len(s.clients))
+	for _, c := range s.clients {
+		res = append(res, stateOf(c.id, c.world.Player))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+func stateOf(id int, p *player.Player) State {
+	return State{ID: id, X: p.Coord.X, Y: p.Coord.Y, Angle: p.Angle, HozAngle: p.HozAngle}
+}
+
+// Step handles what the clients sent, moves their players, and sends everyone the new state
+func (s *Server) Step() {
+	now := time.Now()
+drain:
+	for {
+		select {
+		case p, ok := <-s.packets:
+			if !ok {
+				s.closed = true
+				return
+			}
+			s.handle(p, now)
+		default:
+			break drain
+		}
+	}
+
+	for key, c := range s.clients {
+		if now.Sub(c.seen) > TIMEOUT {
+			log.Printf("player %d timed out", c.id)
+			delete(s.clients, key)
+			continue
+		}
+		// one input a tick, so sending more does not move a player faster
+		if len(c.inputs) > 0 {
+			in := c.inputs[0]
+			c.world.Step(limit(in.Frame))
+			c.ack = in.Seq
+			c.inputs = c.inputs[1:]
+		}
+	}
+	s.Tick++
+
+	players := s.Players()
+	for _, c := range s.clients {
+		s.send(c.addr, Message{Kind: STATE, ID: c.id, Ack: c.ack, Tick: s.Tick, Players: players})
+	}
+}
+
+func (s *Server) handle(p packet, now time.Time) {
+	key := p.addr.String()
+	c, known := s.clients[key]
+	switch p.Kind {
+	case HELLO:
+		if p.Version != VERSION {
+			return
+		}
+		if !known {
+			spawn := s.Spawn
+			pl := &player.Player{Coord: &spawn, Speed: s.Speed}
+			c = &remote{id: s.nextID, addr: p.addr, world: sim.New(pl, s.Map, s.BlockSize, 1)}
+			s.clients[key] = c
+			s.nextID++
+			log.Printf("player %d joined from %v", c.id, p.addr)
+		}
+		// a known client asking again did not get all of the welcome, and the
+		// chunks before the first row it is missing are not sent again
+		c.seen = now
+		starts := chunks(s.Map)
+		for i, start := range starts {
+			end := len(s.Map)
+			if i+1 < len(starts) {
+				end = starts[i+1]
+			}
+			if end <= p.Row {
+				continue
+			}
+			s.send(p.addr, Message{
+				Kind:      WELCOME,
+				Version:   VERSION,
+				ID:        c.id,
+				Map:       s.Map[start:end],
+				Row:       start,
+				Rows:      len(s.Map),
+				BlockSize: s.BlockSize,
+				Speed:     s.Speed,
+				Ack:       c.ack,
+				Players:   []State{stateOf(c.id, c.world.Player)},
+			})
+		}
+	case INPUT:
+		if !known {
+			return
+		}
+		c.seen = now
+		// inputs are resent until acknowledged, so only the new ones are kept
+		last := c.ack
+		if len(c.inputs) > 0 {
+			last = c.inputs[len(c.inputs)-1].Seq
+		}
+		// more than a client keeps pending is more than it can have sent in time
+		for _, in := range p.Inputs {
+			if in.Seq > last && len(c.inputs) < MAX_PENDING {
+				c.inputs = append(c.inputs, in)
+				last = in.Seq
+			}
+		}
+	case BYE:
+		if known {
+			log.Printf("player %d left", c.id)
+			delete(s.clients, key)
+		}
+	}
+}
+
+// limit keeps f within what the game can send, so clients cannot move faster than others
+func limit(f input.Frame) input.Frame {
+	f.Move = clamp(f.Move, 1)
+	f.Strafe = clamp(f.Strafe, 1)
+	f.Turn = clamp(f.Turn, MAX_TURN)
+	f.Look = clamp(f.Look, MAX_LOOK)
+	return f
+}
+
+// clamp puts v between -limit and limit
+func clamp(v, limit float64) float64 {
+	return max(min(v, limit), -limit)
+}
+
+func (s *Server) send(addr *net.UDPAddr, m Message) {
+	data, err := encode(m)
+	if err != nil {
+		log.Printf("could not encode %v message: %v", m.Kind, err)
+		return
+	}
+	if _, err := s.conn.WriteToUDP(data, addr); err != nil {
+		log.Printf("could not send %v message to %v: %v", m.Kind, addr, err)
+	}
+}
+
+// Run steps the server TICK_RATE times a second, until stop is closed or the server is
+func (s *Server) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Second / TICK_RATE)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.Step()
+			if s.closed {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) Close() error {
+	return s.conn.Close()
+}
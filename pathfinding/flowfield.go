@@ -0,0 +1,84 @@
+package pathfinding
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// FlowField knows the cost of the cheapest way to a goal from every cell,
+// so any number of agents can find their way there
+type FlowField struct {
+	Goal raycasting.Cell
+	// Cost is indexed like the map, and is infinite for cells without a way to the goal
+	Cost [][]float64
+	grid *Grid
+}
+
+// FlowField is the flow field towards goal. It is shared with the cache, so do not change it.
+func (g *Grid) FlowField(goal raycasting.Cell) *FlowField {
+	if f, ok := g.fields[goal]; ok {
+		return f
+	}
+	f := &FlowField{Goal: goal, Cost: make([][]float64, len(g.Map)), grid: g}
+	for y, row := range g.Map {
+		f.Cost[y] = make([]float64, len(row))
+		for x := range row {
+			f.Cost[y][x] = math.Inf(1)
+		}
+	}
+	if g.Open(goal) {
+		// moves cost the same both ways, so spreading out from the goal gives the cost to it
+		f.Cost[goal.Y][goal.X] = 0
+		open := &queue{{goal, 0}}
+		for open.Len() > 0 {
+			it := heap.Pop(open).(item)
+			if it.cost > f.Cost[it.cell.Y][it.cell.X] {
+				continue
+			}
+			g.neighbours(it.cell, func(n raycasting.Cell, step float64) {
+				if c := it.cost + step; c < f.Cost[n.Y][n.X] {
+					f.Cost[n.Y][n.X] = c
+					heap.Push(open, item{n, c})
+				}
+			})
+		}
+	}
+	if len(g.fields) >= MAX_CACHED {
+		g.fields = map[raycasting.Cell]*FlowField{}
+	}
+	g.fields[goal] = f
+	return f
+}
+
+// Reachable is whether there is a way from c to the goal
+func (f *FlowField) Reachable(c raycasting.Cell) bool {
+	return f.grid.inside(c) && !math.IsInf(f.Cost[c.Y][c.X], 1)
+}
+
+// Next is the cell to move to from c to get closer to the goal. It is false
+// at the goal, and where there is no way to it.
+func (f *FlowField) Next(c raycasting.Cell) (raycasting.Cell, bool) {
+	if !f.Reachable(c) || c == f.Goal {
+		return c, false
+	}
+	best, bestCost := c, math.Inf(1)
+	f.grid.neighbours(c, func(n raycasting.Cell, step float64) {
+		if cost := f.Cost[n.Y][n.X] + step; cost < bestCost {
+			best, bestCost = n, cost
+		}
+	})
+	return best, best != c
+}
+
+// Direction is the unit vector from the center of c towards the next cell, or false like Next
+func (f *FlowField) Direction(c raycasting.Cell) (float64, float64, bool) {
+	n, ok := f.Next(c)
+	if !ok {
+		return 0, 0, false
+	}
+	dx, dy := float64(n.X-c.X), float64(n.Y-c.Y)
+	l := math.Hypot(dx, dy)
+	return dx / l, dy / l, true
+}
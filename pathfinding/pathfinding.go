@@ -0,0 +1,240 @@
+// Package pathfinding finds ways through the open cells of a map, for one
+// agent with A*, or for many agents going to the same place with flow fields.
+package pathfinding
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// MAX_CACHED is how many paths are kept before the cache is emptied
+const MAX_CACHED = 256
+
+type Options struct {
+	// Diagonal allows moving diagonally between cells
+	Diagonal bool
+	// CutCorners allows diagonal moves past the corner of a wall, which
+	// is otherwise only allowed when both cells beside the move are open
+	CutCorners bool
+}
+
+type pathKey struct {
+	from, to raycasting.Cell
+}
+
+// Grid finds paths in a map. Paths and flow fields are cached until a cell
+// changes, so change cells with SetCell, or call Invalidate after changing the map.
+type Grid struct {
+	Map [][]raycasting.WallType
+	Options
+	paths  map[pathKey][]raycasting.Cell
+	fields map[raycasting.Cell]*FlowField
+}
+
+func NewGrid(m [][]raycasting.WallType, opts Options) *Grid {
+	g := &Grid{Map: m, Options: opts}
+	g.Invalidate()
+	return g
+}
+
+// Invalidate forgets all cached paths and flow fields
+func (g *Grid) Invalidate() {
+	g.paths = map[pathKey][]raycasting.Cell{}
+	g.fields = map[raycasting.Cell]*FlowField{}
+}
+
+// SetCell changes a cell of the map, such as a door opening or closing
+func (g *Grid) SetCell(c raycasting.Cell, w raycasting.WallType) {
+	if g.inside(c) && g.Map[c.Y][c.X] != w {
+		g.Map[c.Y][c.X] = w
+		g.Invalidate()
+	}
+}
+
+func (g *Grid) inside(c raycasting.Cell) bool {
+	return c.Y >= 0 && c.Y < len(g.Map) && c.X >= 0 && c.X < len(g.Map[c.Y])
+}
+
+// Open is whether c is in the map and has no wall
+func (g *Grid) Open(c raycasting.Cell) bool {
+	return g.inside(c) && g.Map[c.Y][c.X] == 0
+}
+
+var (
+	straight  = []raycasting.Cell{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}}
+	diagonals = []raycasting.Cell{{X: 1, Y: 1}, {X: 1, Y: -1}, {X: -1, Y: 1}, {X: -1, Y: -1}}
+)
+
+// neighbours calls fn with the cells that can be moved to from c, and what the move costs
+func (g *Grid) neighbours(c raycasting.Cell, fn func(n raycasting.Cell, cost float64)) {
+	for _, d := range straight {
+		if n := (raycasting.Cell{X: c.X + d.X, Y: c.Y + d.Y}); g.Open(n) {
+			fn(n, 1)
+		}
+	}
+	if !g.Diagonal {
+		return
+	}
+	for _, d := range diagonals {
+		n := raycasting.Cell{X: c.X + d.X, Y: c.Y + d.Y}
+		if !g.Open(n) {
+			continue
+		}
+		sideX, sideY := g.Open(raycasting.Cell{X: c.X + d.X, Y: c.Y}), g.Open(raycasting.Cell{X: c.X, Y: c.Y + d.Y})
+		// squeezing between two walls that touch at the corner is never allowed
+		if (sideX && sideY) || (g.CutCorners && (sideX || sideY)) {
+			fn(n, math.Sqrt2)
+		}
+	}
+}
+
+// estimate is the cost of the shortest possible way from a to b, ignoring walls
+func (g *Grid) estimate(a, b raycasting.Cell) float64 {
+	dx := math.Abs(float64(a.X - b.X))
+	dy := math.Abs(float64(a.Y - b.Y))
+	if !g.Diagonal {
+		return dx + dy
+	}
+	return max(dx, dy) + (math.Sqrt2-1)*min(dx, dy)
+}
+
+// Path is the cheapest way of open cells from one cell to another with A*, starting with from.
+// It is nil if there is no way. The returned path is shared with the cache, so do not change it.
+func (g *Grid) Path(from, to raycasting.Cell) []raycasting.Cell {
+	key := pathKey{from, to}
+	if path, ok := g.paths[key]; ok {
+		return path
+	}
+	path := g.search(from, to)
+	if len(g.paths) >= MAX_CACHED {
+		g.paths = map[pathKey][]raycasting.Cell{}
+	}
+	g.paths[key] = path
+	return path
+}
+
+func (g *Grid) search(from, to raycasting.Cell) []raycasting.Cell {
+	if !g.Open(from) || !g.Open(to) {
+		return nil
+	}
+	cost := map[raycasting.Cell]float64{from: 0}
+	prev := map[raycasting.Cell]raycasting.Cell{}
+	open := &queue{}
+	heap.Push(open, item{from, g.estimate(from, to)})
+	for open.Len() > 0 {
+		c := heap.Pop(open).(item).cell
+		if c == to {
+			path := []raycasting.Cell{c}
+			for c != from {
+				c = prev[c]
+				path = append(path, c)
+			}
+			// the path was found backwards
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path
+		}
+		g.neighbours(c, func(n raycasting.Cell, step float64) {
+			if old, seen := cost[n]; !seen || cost[c]+step < old {
+				cost[n] = cost[c] + step
+				prev[n] = c
+				heap.Push(open, item{n, cost[n] + g.estimate(n, to)})
+			}
+		})
+	}
+	return nil
+}
+
+// Smooth leaves out the cells of a path that can be skipped by walking in a
+// straight line, through open cells only, from the cell before.
+// The first and last cells are always kept.
+func (g *Grid) Smooth(path []raycasting.Cell, blockSize float64) []raycasting.Cell {
+	if len(path) <= 2 {
+		return path
+	}
+	res := []raycasting.Cell{path[0]}
+	for i := 1; i < len(path)-1; i++ {
+		if !g.Visible(res[len(res)-1], path[i+1], blockSize) {
+			res = append(res, path[i])
+		}
+	}
+	return append(res, path[len(path)-1])
+}
+
+// Visible is whether the straight line between the centers of two cells only passes open cells.
+// A line through the corner of a wall is not, unless corners can be cut.
+func (g *Grid) Visible(a, b raycasting.Cell, blockSize float64) bool {
+	for _, c := range raycasting.CellsBetween(center(a, blockSize), center(b, blockSize), blockSize) {
+		if !g.Open(c) {
+			return false
+		}
+	}
+	return g.CutCorners || g.cornersOpen(a, b)
+}
+
+// cornersOpen is whether the cells around every corner the line between the
+// centers of a and b passes exactly are open. CellsBetween only lists one of
+// the two cells the line touches beside such a corner.
+func (g *Grid) cornersOpen(a, b raycasting.Cell) bool {
+	// in steps of half a cell, the line passes a corner at every odd step,
+	// when it goes an odd number of cells both ways
+	dx, dy := b.X-a.X, b.Y-a.Y
+	n := gcd(abs(dx), abs(dy))
+	if n == 0 || (dx/n)%2 == 0 || (dy/n)%2 == 0 {
+		return true
+	}
+	for m := 1; m < 2*n; m += 2 {
+		// the corner is the top left one of cell x, y
+		x := (2*a.X + 1 + m*dx/n) / 2
+		y := (2*a.Y + 1 + m*dy/n) / 2
+		if !g.Open(cell(x-1, y-1)) || !g.Open(cell(x, y-1)) || !g.Open(cell(x-1, y)) || !g.Open(cell(x, y)) {
+			return false
+		}
+	}
+	return true
+}
+
+func cell(x, y int) raycasting.Cell {
+	return raycasting.Cell{X: x, Y: y}
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func center(c raycasting.Cell, blockSize float64) raycasting.Coordinate {
+	return raycasting.Coordinate{X: (float64(c.X) + 0.5) * blockSize, Y: (float64(c.Y) + 0.5) * blockSize}
+}
+
+// item is a cell to look at, with the estimated cost of a way through it
+type item struct {
+	cell raycasting.Cell
+	cost float64
+}
+
+// queue is a priority queue with the cheapest item first
+type queue []item
+
+func (q queue) Len() int           { return len(q) }
+func (q queue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q queue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x any)        { *q = append(*q, x.(item)) }
+func (q *queue) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
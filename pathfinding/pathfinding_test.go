@@ -0,0 +1,152 @@
+package pathfinding
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hvassaa/gaster/maps"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+const blockSize = maps.ROOM_BLOCK_SIZE
+
+// cost is what walking the path costs
+func cost(path []raycasting.Cell) float64 {
+	res := 0.
+	for i := 1; i < len(path); i++ {
+		res += math.Hypot(float64(path[i].X-path[i-1].X), float64(path[i].Y-path[i-1].Y))
+	}
+	return res
+}
+
+func TestPath(t *testing.T) {
+	t.Run("Straight moves around a wall", func(t *testing.T) {
+		g := NewGrid(maps.WithWall(), Options{})
+		path := g.Path(cell(2, 2), cell(12, 2))
+		// down to the opening, across, and back up
+		if len(path) != 1+13+10+13 || path[0] != cell(2, 2) || path[len(path)-1] != cell(12, 2) {
+			t.Fatalf("Path should go around the wall in 37 cells, got: %v", path)
+		}
+		for i := 1; i < len(path); i++ {
+			if cost(path[i-1:i+1]) != 1 {
+				t.Fatalf("Path should only move straight, got: %v to %v", path[i-1], path[i])
+			}
+		}
+	})
+
+	t.Run("Diagonal moves are shorter", func(t *testing.T) {
+		g := NewGrid(maps.WithWall(), Options{Diagonal: true})
+		path := g.Path(cell(2, 2), cell(12, 2))
+		if cost(path) >= 36 {
+			t.Fatalf("Diagonal path should cost less than 36, got: %v", cost(path))
+		}
+		for i := 1; i < len(path); i++ {
+			a, b := path[i-1], path[i]
+			if !g.Open(cell(b.X, a.Y)) || !g.Open(cell(a.X, b.Y)) {
+				t.Fatalf("Path should not cut the corner from %v to %v", a, b)
+			}
+		}
+	})
+
+	t.Run("Corners can be cut", func(t *testing.T) {
+		m := maps.Standard(5, 5)
+		m[1][2] = 1
+		without := NewGrid(m, Options{Diagonal: true}).Path(cell(1, 1), cell(2, 2))
+		with := NewGrid(m, Options{Diagonal: true, CutCorners: true}).Path(cell(1, 1), cell(2, 2))
+		if len(without) != 3 || len(with) != 2 {
+			t.Fatalf("Path should only cut the corner when allowed, got: %v and %v", without, with)
+		}
+		// not even then between two walls touching at the corner
+		m[2][1] = 1
+		if path := NewGrid(m, Options{Diagonal: true, CutCorners: true}).Path(cell(1, 1), cell(2, 2)); path != nil {
+			t.Fatalf("Path should not squeeze between two walls, got: %v", path)
+		}
+	})
+
+	t.Run("No path", func(t *testing.T) {
+		g := NewGrid(maps.WithWall(), Options{Diagonal: true})
+		if path := g.Path(cell(2, 2), cell(10, 2)); path != nil {
+			t.Fatalf("There should be no path into a wall, got: %v", path)
+		}
+		if path := g.Path(cell(2, 2), cell(25, 2)); path != nil {
+			t.Fatalf("There should be no path out of the map, got: %v", path)
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	g := NewGrid(maps.WithWall(), Options{})
+	before := g.Path(cell(2, 2), cell(12, 2))
+	if again := g.Path(cell(2, 2), cell(12, 2)); &again[0] != &before[0] {
+		t.Fatalf("Path should be cached")
+	}
+	field := g.FlowField(cell(12, 2))
+
+	// a door opens in the wall
+	g.SetCell(cell(10, 2), 0)
+	after := g.Path(cell(2, 2), cell(12, 2))
+	if len(after) != 11 {
+		t.Fatalf("Path should go through the door after it opens, got: %v", after)
+	}
+	if g.FlowField(cell(12, 2)) == field {
+		t.Fatalf("Flow field should be made again after a cell changes")
+	}
+}
+
+func TestSmooth(t *testing.T) {
+	g := NewGrid(maps.WithWall(), Options{})
+	path := g.Smooth(g.Path(cell(2, 2), cell(12, 2)), blockSize)
+	if len(path) >= 10 || path[0] != cell(2, 2) || path[len(path)-1] != cell(12, 2) {
+		t.Fatalf("Smoothed path should keep the ends and few corners, got: %v", path)
+	}
+	for i := 1; i < len(path); i++ {
+		if !g.Visible(path[i-1], path[i], blockSize) {
+			t.Fatalf("Smoothed path should walk in straight lines through open cells, got: %v to %v", path[i-1], path[i])
+		}
+	}
+	if short := g.Smooth(g.Path(cell(2, 2), cell(8, 8)), blockSize); len(short) != 2 {
+		t.Fatalf("Path in the open should be smoothed to its ends, got: %v", short)
+	}
+
+	t.Run("Corners of walls", func(t *testing.T) {
+		m := maps.Standard(5, 5)
+		m[1][2] = 1
+		path := []raycasting.Cell{cell(1, 1), cell(1, 2), cell(2, 2)}
+		if smooth := NewGrid(m, Options{}).Smooth(path, blockSize); len(smooth) != 3 {
+			t.Fatalf("Smoothed path should not cut the corner of the wall, got: %v", smooth)
+		}
+		if smooth := NewGrid(m, Options{CutCorners: true}).Smooth(path, blockSize); len(smooth) != 2 {
+			t.Fatalf("Smoothed path should cut the corner when allowed, got: %v", smooth)
+		}
+		if NewGrid(m, Options{}).Visible(cell(1, 1), cell(3, 3), blockSize) {
+			t.Fatalf("Line through the corner of the wall should not be visible")
+		}
+	})
+}
+
+func TestFlowField(t *testing.T) {
+	g := NewGrid(maps.WithWall(), Options{Diagonal: true})
+	f := g.FlowField(cell(12, 2))
+	for _, start := range []raycasting.Cell{cell(2, 2), cell(5, 17), cell(18, 18)} {
+		c := start
+		for i := 0; i < 100; i++ {
+			next, ok := f.Next(c)
+			if !ok {
+				break
+			}
+			c = next
+		}
+		if c != f.Goal {
+			t.Fatalf("Following the field from %v should reach the goal, got: %v", start, c)
+		}
+		if want := cost(g.Path(start, f.Goal)); math.Abs(f.Cost[start.Y][start.X]-want) > 1e-9 {
+			t.Fatalf("Field cost from %v should be the cost of the A* path %v, got: %v", start, want, f.Cost[start.Y][start.X])
+		}
+	}
+	if f.Reachable(cell(10, 5)) {
+		t.Fatalf("Walls should not reach the goal")
+	}
+	if dx, dy, ok := f.Direction(cell(11, 2)); !ok || dx != 1 || dy != 0 {
+		t.Fatalf("Direction next to the goal should point at it, got: %v %v", dx, dy)
+	}
+}
@@ -10,6 +10,8 @@ type Player struct {
 	Coord           *raycasting.Coordinate
 	Angle, HozAngle float64
 	Speed           float64
+	Health          int
+	MaxHealth       int
 }
 
 // TODO fix strafing and moving extra speed
@@ -31,3 +33,14 @@ func (p *Player) MoveWithAngle(multiplier, angle float64) {
 func (p *Player) IncreaseHozAngle(delta float64) {
 	p.HozAngle = max(min(p.HozAngle+delta/2., 180), -180)
 }
+
+// Walk moves the player like MoveWithAngle, unless a wall is closer than
+// half a block in that direction. It reports whether the player moved.
+func (p *Player) Walk(multiplier, angle, blockSize float64, m [][]raycasting.WallType) bool {
+	ray, err := raycasting.CastRay(*p.Coord, raycasting.NormalizeAngle(p.Angle+angle), blockSize, m)
+	if err != nil || ray.Coord.DistanceTo(*p.Coord) <= blockSize/2 {
+		return false
+	}
+	p.MoveWithAngle(multiplier, angle)
+	return true
+}
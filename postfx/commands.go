@@ -0,0 +1,64 @@
+package postfx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hvassaa/gaster/console"
+)
+
+// switchOf is the On field of an effect, or nil for effects that cannot be turned off
+func switchOf(e Effect) *bool {
+	switch e := e.(type) {
+	case *Scanlines:
+		return &e.On
+	case *Vignette:
+		return &e.On
+	case *ColorGrade:
+		return &e.On
+	case *DamageFlash:
+		return &e.On
+	}
+	return nil
+}
+
+func name(e Effect) string {
+	switch e.(type) {
+	case *Scanlines:
+		return "scanlines"
+	case *Vignette:
+		return "vignette"
+	case *ColorGrade:
+		return "colorgrade"
+	case *DamageFlash:
+		return "damageflash"
+	}
+	return fmt.Sprintf("%T", e)
+}
+
+// RegisterCommands adds console commands for the effects in the pipeline
+func (p *Pipeline) RegisterCommands(c *console.Console) {
+	c.Register(console.Command{
+		Name:  "fx",
+		Usage: "<effect>",
+		Help:  "toggles a post processing effect, or lists them",
+		Run: func(args []string) (string, error) {
+			if len(args) == 0 {
+				res := ""
+				for _, e := range p.Effects {
+					if on := switchOf(e); on != nil {
+						res += fmt.Sprintf("%v %v\n", name(e), *on)
+					}
+				}
+				return res + "only effects turned on in the settings are loaded", nil
+			}
+			for _, e := range p.Effects {
+				if on := switchOf(e); on != nil && name(e) == args[0] {
+					*on = !*on
+					return fmt.Sprintf("%v %v", args[0], *on), nil
+				}
+			}
+			return "", errors.New("no such effect loaded: " + args[0])
+		},
+	})
+}
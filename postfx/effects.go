@@ -0,0 +1,172 @@
+package postfx
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+type Scanlines struct {
+	On        bool
+	Intensity float64
+	// Spacing is the distance in pixels between the lines
+	Spacing float64
+	shader  *ebiten.Shader
+}
+
+func NewScanlines(intensity, spacing float64) *Scanlines {
+	return &Scanlines{On: true, Intensity: intensity, Spacing: max(spacing, 1), shader: loadShader("scanlines")}
+}
+
+func (s *Scanlines) Enabled() bool {
+	return s.On
+}
+
+func (s *Scanlines) Apply(dst, src *ebiten.Image) {
+	drawShader(dst, src, nil, s.shader, map[string]any{
+		"Intensity": float32(s.Intensity),
+		"Spacing":   float32(s.Spacing),
+	})
+}
+
+type Vignette struct {
+	On       bool
+	Strength float64
+	// Radius is where the darkening starts, and Softness how far it takes to reach full Strength,
+	// both as fractions of the distance from the center to a corner
+	Radius, Softness float64
+	shader           *ebiten.Shader
+}
+
+func NewVignette(strength float64) *Vignette {
+	return &Vignette{On: true, Strength: strength, Radius: 0.4, Softness: 0.6, shader: loadShader("vignette")}
+}
+
+func (v *Vignette) Enabled() bool {
+	return v.On
+}
+
+func (v *Vignette) Apply(dst, src *ebiten.Image) {
+	drawShader(dst, src, nil, v.shader, map[string]any{
+		"Strength": float32(v.Strength),
+		"Radius":   float32(v.Radius),
+		"Softness": float32(v.Softness),
+	})
+}
+
+// LUT_WIDTH and LUT_HEIGHT are the size of color grading LUTs, 16 slices of 16x16
+const (
+	LUT_WIDTH  = 256
+	LUT_HEIGHT = 16
+)
+
+// IdentityLUT is a LUT that does not change any colors
+func IdentityLUT() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, LUT_WIDTH, LUT_HEIGHT))
+	for b := 0; b < 16; b++ {
+		for g := 0; g < 16; g++ {
+			for r := 0; r < 16; r++ {
+				img.Set(b*16+r, g, color.RGBA{uint8(r * 17), uint8(g * 17), uint8(b * 17), 255})
+			}
+		}
+	}
+	return img
+}
+
+type ColorGrade struct {
+	On        bool
+	Intensity float64
+	lut       *ebiten.Image
+	// shader images must have the same size, so the LUT is copied into an image the size of the source
+	canvas *ebiten.Image
+	shader *ebiten.Shader
+}
+
+// NewColorGrade creates a color grading effect from a 256x16 LUT, or an identity LUT if lut is nil
+func NewColorGrade(lut image.Image, intensity float64) (*ColorGrade, error) {
+	if lut == nil {
+		lut = IdentityLUT()
+	}
+	if lut.Bounds().Dx() != LUT_WIDTH || lut.Bounds().Dy() != LUT_HEIGHT {
+		return nil, fmt.Errorf("LUT should be %dx%d, got: %dx%d", LUT_WIDTH, LUT_HEIGHT, lut.Bounds().Dx(), lut.Bounds().Dy())
+	}
+	return &ColorGrade{On: true, Intensity: intensity, lut: ebiten.NewImageFromImage(lut), shader: loadShader("colorgrade")}, nil
+}
+
+func (c *ColorGrade) Enabled() bool {
+	return c.On
+}
+
+func (c *ColorGrade) Apply(dst, src *ebiten.Image) {
+	b := src.Bounds()
+	if b.Dx() < LUT_WIDTH || b.Dy() < LUT_HEIGHT {
+		// too small to hold the LUT, so leave the image as it is
+		dst.DrawImage(src, &ebiten.DrawImageOptions{Blend: ebiten.BlendCopy})
+		return
+	}
+	if c.canvas == nil || c.canvas.Bounds() != b {
+		if c.canvas != nil {
+			c.canvas.Dispose()
+		}
+		c.canvas = ebiten.NewImage(b.Dx(), b.Dy())
+		c.canvas.DrawImage(c.lut, nil)
+	}
+	drawShader(dst, src, c.canvas, c.shader, map[string]any{
+		"Intensity": float32(c.Intensity),
+	})
+}
+
+// DamageFlash tints the image, strongest at the edges, and fades out.
+// Every player sharing the screen has a flash of their own.
+type DamageFlash struct {
+	On    bool
+	Color color.RGBA
+	// Fade is how much of the flash is left after each tick
+	Fade float64
+	// Player is whose flash Apply shows
+	Player    int
+	intensity []float64
+	shader    *ebiten.Shader
+}
+
+func NewDamageFlash(clr color.RGBA) *DamageFlash {
+	return &DamageFlash{On: true, Color: clr, Fade: 0.9, shader: loadShader("damageflash")}
+}
+
+// Flash starts a flash for a player, strength is from 0 to 1
+func (d *DamageFlash) Flash(player int, strength float64) {
+	for len(d.intensity) <= player {
+		d.intensity = append(d.intensity, 0)
+	}
+	d.intensity[player] = max(d.intensity[player], min(strength, 1))
+}
+
+func (d *DamageFlash) Update() {
+	for i := range d.intensity {
+		d.intensity[i] *= d.Fade
+		if d.intensity[i] < 0.01 {
+			d.intensity[i] = 0
+		}
+	}
+}
+
+// current is the intensity of the flash of Player
+func (d *DamageFlash) current() float64 {
+	if d.Player < 0 || d.Player >= len(d.intensity) {
+		return 0
+	}
+	return d.intensity[d.Player]
+}
+
+func (d *DamageFlash) Enabled() bool {
+	return d.On && d.current() > 0
+}
+
+func (d *DamageFlash) Apply(dst, src *ebiten.Image) {
+	drawShader(dst, src, nil, d.shader, map[string]any{
+		"FlashColor": []float32{float32(d.Color.R) / 255, float32(d.Color.G) / 255, float32(d.Color.B) / 255},
+		"Intensity":  float32(d.current()),
+	})
+}
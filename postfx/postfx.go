@@ -0,0 +1,156 @@
+// Package postfx applies a chain of shader effects to an image, after it has been rendered
+package postfx
+
+import (
+	"embed"
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hvassaa/gaster/settings"
+	"github.com/hvassaa/gaster/texture"
+)
+
+//go:embed shaders/*.kage
+var shaders embed.FS
+
+func loadShader(name string) *ebiten.Shader {
+	src, err := shaders.ReadFile("shaders/" + name + ".kage")
+	if err != nil {
+		panic(err)
+	}
+	shader, err := ebiten.NewShader(src)
+	if err != nil {
+		panic(name + ": " + err.Error())
+	}
+	return shader
+}
+
+type Effect interface {
+	Enabled() bool
+	// Apply draws src to dst with the effect. They have the same size, and both start at (0, 0).
+	Apply(dst, src *ebiten.Image)
+}
+
+// Updater is implemented by effects that change over time, and is called once per tick
+type Updater interface {
+	Update()
+}
+
+// drawShader draws src to dst with the shader, replacing what was in dst.
+// src1 is an optional second image, which must have the same size as src.
+func drawShader(dst, src, src1 *ebiten.Image, shader *ebiten.Shader, uniforms map[string]any) {
+	op := &ebiten.DrawRectShaderOptions{}
+	op.Images[0] = src
+	op.Images[1] = src1
+	op.Uniforms = uniforms
+	op.Blend = ebiten.BlendCopy
+	dst.DrawRectShader(src.Bounds().Dx(), src.Bounds().Dy(), shader, op)
+}
+
+type Pipeline struct {
+	Effects []Effect
+	// effects are applied back and forth between these
+	buffers [2]*ebiten.Image
+}
+
+// New creates a pipeline with the effects turned on in the settings, in a fixed order.
+// If the LUT in the settings cannot be used, the identity LUT is, and the error
+// is returned with the pipeline.
+func New(cfg settings.Effects) (*Pipeline, error) {
+	p := &Pipeline{}
+	var err error
+	if cfg.ColorGrade {
+		var lut image.Image
+		if cfg.LUT != "" {
+			lut, err = texture.ReadImage(cfg.LUT)
+		}
+		var grade *ColorGrade
+		if err == nil {
+			grade, err = NewColorGrade(lut, cfg.ColorGradeIntensity)
+		}
+		if err != nil {
+			grade, _ = NewColorGrade(nil, cfg.ColorGradeIntensity)
+		}
+		p.Effects = append(p.Effects, grade)
+	}
+	if cfg.Vignette {
+		p.Effects = append(p.Effects, NewVignette(cfg.VignetteStrength))
+	}
+	if cfg.DamageFlash {
+		p.Effects = append(p.Effects, NewDamageFlash(color.RGBA{200, 0, 0, 255}))
+	}
+	if cfg.Scanlines {
+		p.Effects = append(p.Effects, NewScanlines(cfg.ScanlineIntensity, cfg.ScanlineSpacing))
+	}
+	return p, err
+}
+
+func (p *Pipeline) Update() {
+	for _, e := range p.Effects {
+		if u, ok := e.(Updater); ok {
+			u.Update()
+		}
+	}
+}
+
+// Flash starts a damage flash for a player sharing the screen, if the pipeline has one
+func (p *Pipeline) Flash(player int, strength float64) {
+	for _, e := range p.Effects {
+		if f, ok := e.(*DamageFlash); ok {
+			f.Flash(player, strength)
+		}
+	}
+}
+
+// ShowPlayer makes Apply show the damage flash of a player, for their part of the screen
+func (p *Pipeline) ShowPlayer(player int) {
+	for _, e := range p.Effects {
+		if f, ok := e.(*DamageFlash); ok {
+			f.Player = player
+		}
+	}
+}
+
+func (p *Pipeline) buffer(i, width, height int) *ebiten.Image {
+	b := p.buffers[i]
+	if b == nil || b.Bounds().Dx() != width || b.Bounds().Dy() != height {
+		if b != nil {
+			b.Dispose()
+		}
+		b = ebiten.NewImage(width, height)
+		p.buffers[i] = b
+	}
+	return b
+}
+
+// Apply runs the enabled effects on img in place. img may be a sub image.
+func (p *Pipeline) Apply(img *ebiten.Image) {
+	var enabled []Effect
+	for _, e := range p.Effects {
+		if e.Enabled() {
+			enabled = append(enabled, e)
+		}
+	}
+	if len(enabled) == 0 {
+		return
+	}
+
+	bounds := img.Bounds()
+	src := p.buffer(0, bounds.Dx(), bounds.Dy())
+	dst := p.buffer(1, bounds.Dx(), bounds.Dy())
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(-float64(bounds.Min.X), -float64(bounds.Min.Y))
+	op.Blend = ebiten.BlendCopy
+	src.DrawImage(img, op)
+
+	for _, e := range enabled {
+		e.Apply(dst, src)
+		src, dst = dst, src
+	}
+
+	op = &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(bounds.Min.X), float64(bounds.Min.Y))
+	op.Blend = ebiten.BlendCopy
+	img.DrawImage(src, op)
+}
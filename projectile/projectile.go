@@ -0,0 +1,230 @@
+// Package projectile has things that fly through the world, like rockets,
+// fireballs and grenades, which hit what they run into instead of hitting
+// the moment they are fired. How each kind flies and explodes is data.
+package projectile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// NO_TARGET is the target of explosions that did not hit one directly
+const NO_TARGET = -1
+
+// Type is how a kind of projectile flies and explodes. Distances are in blocks.
+type Type struct {
+	Name string
+	// Speed is in blocks per tick, and Friction the fraction of it lost every tick
+	Speed, Friction float64
+	// Radius is how wide it is, for hitting things
+	Radius float64
+	// Damage is done to what it hits directly
+	Damage int
+	// SplashDamage is done to everything within SplashRadius that the explosion
+	// can reach, falling off towards the edge
+	SplashRadius float64
+	SplashDamage int
+	// Bounces is how many times it bounces off walls before exploding against one,
+	// and Restitution the fraction of its speed kept in each bounce
+	Bounces     int
+	Restitution float64
+	// Lifetime is the ticks before it explodes by itself, or 0 to fly until it
+	// hits something, which is only allowed without friction
+	Lifetime int
+	// Size, Lift and Color are how it is drawn
+	Size, Lift float64
+	Color      color.RGBA
+}
+
+// LoadTypes reads a JSON list of types, by name
+func LoadTypes(path string) (map[string]*Type, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []*Type
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	types := map[string]*Type{}
+	for _, t := range list {
+		if t.Name == "" {
+			return nil, errors.New(path + ": type without a name")
+		}
+		if t.Speed <= 0 || t.Radius <= 0 {
+			return nil, errors.New(path + ": " + t.Name + " should have a speed and a radius")
+		}
+		// friction stops it, and then it would lie there forever
+		if t.Friction > 0 && t.Lifetime <= 0 {
+			return nil, errors.New(path + ": " + t.Name + " should have a lifetime, as it has friction")
+		}
+		types[t.Name] = t
+	}
+	return types, nil
+}
+
+type Projectile struct {
+	Type  *Type
+	Coord raycasting.Coordinate
+	// VX and VY are how far it moves in the next tick
+	VX, VY float64
+	// Owner is what launched it, which it flies through, like a target's Entity
+	Owner any
+	// Age is the ticks it has flown
+	Age     int
+	bounces int
+}
+
+// Target is something projectiles can hit, such as an enemy or a player
+type Target struct {
+	Coord  raycasting.Coordinate
+	Radius float64
+	// Entity is what the target is, to know the owner of a projectile
+	Entity any
+}
+
+// Hit is damage an explosion did to a target
+type Hit struct {
+	Target int
+	Entity any
+	Damage int
+}
+
+// Explosion is where a projectile ended, with what it hurt
+type Explosion struct {
+	Projectile *Projectile
+	Coord      raycasting.Coordinate
+	// Target is the target hit directly, or NO_TARGET
+	Target int
+	Hits   []Hit
+}
+
+// Group is the projectiles flying in a map
+type Group struct {
+	Projectiles []*Projectile
+	Types       map[string]*Type
+	Map         [][]raycasting.WallType
+	BlockSize   float64
+}
+
+func NewGroup(m [][]raycasting.WallType, blockSize float64, types map[string]*Type) *Group {
+	return &Group{Types: types, Map: m, BlockSize: blockSize}
+}
+
+// Launch adds a projectile of the named type at c, flying towards angle
+func (g *Group) Launch(name string, c raycasting.Coordinate, angle float64, owner any) (*Projectile, error) {
+	t, ok := g.Types[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown projectile type %v", name)
+	}
+	speed := t.Speed * g.BlockSize
+	p := &Projectile{Type: t, Coord: c, VX: math.Cos(angle) * speed, VY: math.Sin(angle) * speed, Owner: owner}
+	g.Projectiles = append(g.Projectiles, p)
+	return p, nil
+}
+
+// Update moves every projectile a tick, and returns the explosions of
+// those that hit something or ran out of time
+func (g *Group) Update(targets []Target) []Explosion {
+	var explosions []Explosion
+	flying := g.Projectiles[:0]
+	for _, p := range g.Projectiles {
+		if target, ok := g.move(p, targets); ok {
+			explosions = append(explosions, g.explode(p, target, targets))
+			continue
+		}
+		p.Age++
+		if p.Type.Lifetime > 0 && p.Age >= p.Type.Lifetime {
+			explosions = append(explosions, g.explode(p, NO_TARGET, targets))
+			continue
+		}
+		flying = append(flying, p)
+	}
+	g.Projectiles = flying
+	return explosions
+}
+
+// move moves p in steps short enough not to pass through walls or targets.
+// It reports whether p hit something and should explode, and the target it hit.
+func (g *Group) move(p *Projectile, targets []Target) (int, bool) {
+	steps := max(math.Ceil(math.Hypot(p.VX, p.VY)/(g.BlockSize/4)), 1)
+	for i := 0.; i < steps; i++ {
+		// each axis is tried alone, so it bounces off the side of the wall it hits
+		x := p.Coord.X + p.VX/steps
+		if g.wall(x, p.Coord.Y) {
+			if !g.bounce(p) {
+				return NO_TARGET, true
+			}
+			p.VX = -p.VX
+			x = p.Coord.X
+		}
+		y := p.Coord.Y + p.VY/steps
+		if g.wall(x, y) {
+			if !g.bounce(p) {
+				return NO_TARGET, true
+			}
+			p.VY = -p.VY
+			y = p.Coord.Y
+		}
+		p.Coord = raycasting.Coordinate{X: x, Y: y}
+		for j, t := range targets {
+			if t.Entity != nil && t.Entity == p.Owner {
+				continue
+			}
+			if p.Coord.DistanceTo(t.Coord) < p.Type.Radius*g.BlockSize+t.Radius {
+				return j, true
+			}
+		}
+	}
+	p.VX *= 1 - p.Type.Friction
+	p.VY *= 1 - p.Type.Friction
+	return NO_TARGET, false
+}
+
+// bounce slows p down for bouncing off a wall, and reports whether it has bounces left
+func (g *Group) bounce(p *Projectile) bool {
+	if p.bounces >= p.Type.Bounces {
+		return false
+	}
+	p.bounces++
+	p.VX *= p.Type.Restitution
+	p.VY *= p.Type.Restitution
+	return true
+}
+
+// wall is whether x, y is in a wall, or outside the map
+func (g *Group) wall(x, y float64) bool {
+	cx, cy := int(math.Floor(x/g.BlockSize)), int(math.Floor(y/g.BlockSize))
+	return cy < 0 || cy >= len(g.Map) || cx < 0 || cx >= len(g.Map[cy]) || g.Map[cy][cx] != 0
+}
+
+// explode damages the target hit directly, and the targets within the splash
+// radius, less the farther their edge is from the explosion. Walls block the splash.
+func (g *Group) explode(p *Projectile, direct int, targets []Target) Explosion {
+	damage := make([]int, len(targets))
+	if direct != NO_TARGET {
+		damage[direct] += p.Type.Damage
+	}
+	if radius := p.Type.SplashRadius * g.BlockSize; radius > 0 {
+		for i, t := range targets {
+			dist := max(p.Coord.DistanceTo(t.Coord)-t.Radius, 0)
+			if dist >= radius || !raycasting.HasLineOfSight(p.Coord, t.Coord, g.BlockSize, g.Map) {
+				continue
+			}
+			damage[i] += int(math.Round(float64(p.Type.SplashDamage) * (1 - dist/radius)))
+		}
+	}
+	e := Explosion{Projectile: p, Coord: p.Coord, Target: direct}
+	for i, d := range damage {
+		if d > 0 {
+			e.Hits = append(e.Hits, Hit{i, targets[i].Entity, d})
+		}
+	}
+	return e
+}
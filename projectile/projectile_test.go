@@ -0,0 +1,153 @@
+package projectile
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hvassaa/gaster/maps"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+const blockSize = maps.ROOM_BLOCK_SIZE
+
+var rocket = &Type{Name: "rocket", Speed: 0.5, Radius: 0.1, Damage: 20, SplashRadius: 2, SplashDamage: 40}
+
+// newGroup is the room with the given projectile types
+func newGroup(types ...*Type) *Group {
+	byName := map[string]*Type{}
+	for _, t := range types {
+		byName[t.Name] = t
+	}
+	return NewGroup(maps.Room(), blockSize, byName)
+}
+
+// fly updates g until something explodes, or for at most ticks
+func fly(g *Group, targets []Target, ticks int) []Explosion {
+	for i := 0; i < ticks; i++ {
+		if e := g.Update(targets); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func TestFlight(t *testing.T) {
+	t.Run("Walls stop projectiles", func(t *testing.T) {
+		g := newGroup(rocket)
+		g.Launch("rocket", raycasting.Coordinate{X: 410, Y: 410}, 0, nil)
+		g.Update(nil)
+		if p := g.Projectiles[0]; math.Abs(p.Coord.X-430) > 1e-9 || p.Coord.Y != 410 {
+			t.Fatalf("Rocket should fly half a block east, got: %v", p.Coord)
+		}
+		e := fly(g, nil, 100)
+		wall := maps.ROOM_EAST_WALL * blockSize
+		if len(e) != 1 || e[0].Target != NO_TARGET || e[0].Coord.X >= wall || e[0].Coord.X < wall-rocket.Speed*blockSize {
+			t.Fatalf("Rocket should explode right before the east wall, got: %+v", e)
+		}
+		if len(g.Projectiles) != 0 {
+			t.Fatalf("Exploded rocket should be gone, got: %v", len(g.Projectiles))
+		}
+	})
+
+	t.Run("Unknown type", func(t *testing.T) {
+		if _, err := newGroup(rocket).Launch("arrow", raycasting.Coordinate{}, 0, nil); err == nil {
+			t.Fatalf("Launching an unknown type should fail")
+		}
+	})
+
+	t.Run("Bounce", func(t *testing.T) {
+		ball := &Type{Name: "ball", Speed: 0.5, Radius: 0.1, Bounces: 1, Restitution: 0.5}
+		g := newGroup(ball)
+		g.Launch("ball", raycasting.Coordinate{X: 740, Y: 410}, 0, nil)
+		if e := fly(g, nil, 2); e != nil {
+			t.Fatalf("Ball should bounce off the wall, got: %+v", e)
+		}
+		p := g.Projectiles[0]
+		if p.VX != -10 || p.Coord.X >= maps.ROOM_EAST_WALL*blockSize {
+			t.Fatalf("Ball should bounce back at half the speed, got: %v at %v", p.VX, p.Coord)
+		}
+		if e := fly(g, nil, 200); len(e) != 1 {
+			t.Fatalf("Ball should explode on the next wall, got: %+v", e)
+		}
+	})
+
+	t.Run("Friction and lifetime", func(t *testing.T) {
+		grenade := &Type{Name: "grenade", Speed: 0.5, Friction: 0.5, Radius: 0.1, Lifetime: 10}
+		g := newGroup(grenade)
+		g.Launch("grenade", raycasting.Coordinate{X: 410, Y: 410}, 0, nil)
+		if e := fly(g, nil, 9); e != nil {
+			t.Fatalf("Grenade should not explode before its lifetime, got: %+v", e)
+		}
+		// it slows down to a stop after 20, 10, 5... so just before 40 blocks to the east
+		if p := g.Projectiles[0]; p.Coord.X >= 450 || p.Coord.X < 449 {
+			t.Fatalf("Grenade should slow down, got: %v", p.Coord)
+		}
+		if e := g.Update(nil); len(e) != 1 {
+			t.Fatalf("Grenade should explode after its lifetime, got: %+v", e)
+		}
+	})
+}
+
+func TestHits(t *testing.T) {
+	owner, enemy := new(int), new(int)
+	targets := func() []Target {
+		return []Target{
+			{raycasting.Coordinate{X: 410, Y: 410}, 10, owner},
+			{raycasting.Coordinate{X: 600, Y: 410}, 10, enemy},
+			// near the enemy, but behind a wall
+			{raycasting.Coordinate{X: 590, Y: 490}, 10, nil},
+			{raycasting.Coordinate{X: 600, Y: 700}, 10, nil},
+		}
+	}
+
+	t.Run("Direct hit and splash", func(t *testing.T) {
+		g := newGroup(rocket)
+		g.Map[11][13], g.Map[11][14], g.Map[11][15] = 1, 1, 1
+		g.Launch("rocket", raycasting.Coordinate{X: 410, Y: 410}, 0, owner)
+		e := fly(g, targets(), 100)
+		if len(e) != 1 || e[0].Target != 1 {
+			t.Fatalf("Rocket should fly past its owner and hit the enemy, got: %+v", e)
+		}
+		hits := e[0].Hits
+		if len(hits) != 1 || hits[0].Target != 1 || hits[0].Damage != rocket.Damage+rocket.SplashDamage {
+			t.Fatalf("Only the enemy should be hurt, by the hit and the whole splash, got: %+v", hits)
+		}
+	})
+
+	t.Run("Splash falls off", func(t *testing.T) {
+		g := newGroup(rocket)
+		ts := targets()
+		ts[0].Coord.X = 100
+		g.Launch("rocket", ts[0].Coord, raycasting.PI, owner)
+		e := fly(g, ts, 100)
+		// the rocket explodes at the west wall, a block and a half from the owner's edge
+		if len(e) != 1 || len(e[0].Hits) != 1 || e[0].Hits[0].Target != 0 {
+			t.Fatalf("Splash should hurt the owner, got: %+v", e)
+		}
+		if d := e[0].Hits[0].Damage; d <= 0 || d >= rocket.SplashDamage {
+			t.Fatalf("Splash should be weaker away from the explosion, got: %v", d)
+		}
+	})
+}
+
+func TestLoad(t *testing.T) {
+	types, err := LoadTypes("../resources/projectiles/types.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := types["rocket"]; !ok {
+		t.Fatalf("Rocket type should be loaded, got: %v", types)
+	}
+
+	t.Run("Friction without a lifetime", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "types.json")
+		if err := os.WriteFile(path, []byte(`[{"Name": "stone", "Speed": 0.3, "Radius": 0.1, "Friction": 0.05}]`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadTypes(path); err == nil {
+			t.Fatalf("Type that stops and never explodes should not load")
+		}
+	})
+}
@@ -26,11 +26,18 @@ type Coordinate struct {
 	X, Y float64
 }
 
+// Cell is the index of a block in the map
+type Cell struct {
+	X, Y int
+}
+
 type Ray struct {
 	Coord     Coordinate
 	Dir       Direction
 	Wt        WallType
 	Ang, Dist float64
+	// Cell is the wall block that was hit
+	Cell Cell
 }
 
 func (c Direction) asText() string {
@@ -43,6 +50,10 @@ func (c Direction) asText() string {
 	panic("unknown direction")
 }
 
+func (c Direction) String() string {
+	return c.asText()
+}
+
 func (c Coordinate) DistanceTo(c2 Coordinate) float64 {
 	xDiff := math.Abs(c.X - c2.X)
 	yDiff := math.Abs(c.Y - c2.Y)
@@ -58,6 +69,17 @@ func NormalizeAngle(angle float64) float64 {
 	return angle
 }
 
+// RelativeAngle is angle a relative to b, from -PI to PI
+func RelativeAngle(a, b float64) float64 {
+	d := math.Mod(a-b, PI_TWO)
+	if d > PI {
+		d -= PI_TWO
+	} else if d < -PI {
+		d += PI_TWO
+	}
+	return d
+}
+
 func keepCasting(ix, iy, xOffset, yOffset, blockSize float64, direction Direction, m [][]WallType) (*Ray, error) {
 	y_size := len(m)
 	x_size := len(m[0])
@@ -86,6 +108,7 @@ func keepCasting(ix, iy, xOffset, yOffset, blockSize float64, direction Directio
 				Coord: Coordinate{ix, iy},
 				Dir:   direction,
 				Wt:    wallType,
+				Cell:  Cell{xx, yy},
 			}, nil
 		}
 
@@ -171,3 +194,92 @@ func CastRay(coordinate Coordinate, angle, blockSize float64, m [][]WallType) (*
 	}
 	return hozRay, nil
 }
+
+// HasLineOfSight reports whether no wall blocks the straight line between from and to.
+// A wall hit exactly at to does not count as blocking, so points on wall faces can be seen.
+func HasLineOfSight(from, to Coordinate, blockSize float64, m [][]WallType) bool {
+	dist := from.DistanceTo(to)
+	if dist == 0 {
+		return true
+	}
+	angle := NormalizeAngle(math.Atan2(to.Y-from.Y, to.X-from.X))
+	ray, err := CastRay(from, angle, blockSize, m)
+	if err != nil {
+		return true
+	}
+	return ray.Dist >= dist-blockSize/100
+}
+
+// CastRays casts noOfRays rays spread evenly over fov (in radians), centered on angle.
+// Rays that do not hit anything are left as the zero Ray.
+func CastRays(coordinate Coordinate, angle, fov float64, noOfRays int, blockSize float64, m [][]WallType) []Ray {
+	rays := make([]Ray, noOfRays)
+	if noOfRays == 1 {
+		ray, err := CastRay(coordinate, NormalizeAngle(angle), blockSize, m)
+		if err == nil {
+			rays[0] = *ray
+		}
+		return rays
+	}
+	perRay := fov / float64(noOfRays-1)
+	for i := range rays {
+		rayAngle := NormalizeAngle(angle - fov/2 + float64(i)*perRay)
+		ray, err := CastRay(coordinate, rayAngle, blockSize, m)
+		if err != nil {
+			continue
+		}
+		rays[i] = *ray
+	}
+	return rays
+}
+
+// CellsBetween returns the cells the line from "from" to "to" passes through, in order.
+// Both the cells of "from" and "to" are included.
+func CellsBetween(from, to Coordinate, blockSize float64) []Cell {
+	x := int(math.Floor(from.X / blockSize))
+	y := int(math.Floor(from.Y / blockSize))
+	endX := int(math.Floor(to.X / blockSize))
+	endY := int(math.Floor(to.Y / blockSize))
+	cells := []Cell{{x, y}}
+
+	dx, dy := to.X-from.X, to.Y-from.Y
+	stepX, stepY := 1, 1
+	if dx < 0 {
+		stepX = -1
+	}
+	if dy < 0 {
+		stepY = -1
+	}
+	// how far along the line (from 0 to 1) the next vertical and horizontal grid lines are,
+	// and how far apart the grid lines are
+	tMaxX, tMaxY := math.Inf(1), math.Inf(1)
+	tDeltaX, tDeltaY := math.Inf(1), math.Inf(1)
+	if dx != 0 {
+		nextX := float64(x) * blockSize
+		if stepX > 0 {
+			nextX += blockSize
+		}
+		tMaxX = (nextX - from.X) / dx
+		tDeltaX = blockSize / math.Abs(dx)
+	}
+	if dy != 0 {
+		nextY := float64(y) * blockSize
+		if stepY > 0 {
+			nextY += blockSize
+		}
+		tMaxY = (nextY - from.Y) / dy
+		tDeltaY = blockSize / math.Abs(dy)
+	}
+
+	for (x != endX || y != endY) && min(tMaxX, tMaxY) <= 1 {
+		if tMaxX < tMaxY {
+			x += stepX
+			tMaxX += tDeltaX
+		} else {
+			y += stepY
+			tMaxY += tDeltaY
+		}
+		cells = append(cells, Cell{x, y})
+	}
+	return cells
+}
@@ -2,10 +2,10 @@ package raycasting
 
 import (
 	"math"
+	"reflect"
 	"testing"
 )
 
-
 func closeTo(a, b float64) bool {
 	return math.Abs(a-b) <= 0.01
 }
@@ -20,17 +20,17 @@ func TestHorizontalRayAngleInvalidAngles(t *testing.T) {
 
 	t.Run("Looking directly right", func(t *testing.T) {
 		angle := 0.
-		c, _, _ := castRayHorizontal(c, angle, blockSize, m)
-		if !c.IsInvalid() {
-			t.Fatalf("Coordinate should be invalid with angle %v, got: %v", angle, c)
+		ray, err := castRayHorizontal(c, angle, blockSize, m)
+		if err == nil {
+			t.Fatalf("Ray should not hit with angle %v, got: %v", angle, ray.Coord)
 		}
 	})
 
 	t.Run("Looking directly left", func(t *testing.T) {
 		angle := PI
-		c, _, _ := castRayHorizontal(c, angle, blockSize, m)
-		if !c.IsInvalid() {
-			t.Fatalf("Coordinate should be invalid with angle %v, got: %v", angle, c)
+		ray, err := castRayHorizontal(c, angle, blockSize, m)
+		if err == nil {
+			t.Fatalf("Ray should not hit with angle %v, got: %v", angle, ray.Coord)
 		}
 	})
 }
@@ -53,7 +53,11 @@ func TestHorizontalRay(t *testing.T) {
 	t.Run("Looking directly up", func(t *testing.T) {
 		angle := PI_HALF
 		expectedX, expectedY := 7.5, 10.
-		c, _, _ := castRayHorizontal(c, angle, blockSize, m)
+		ray, err := castRayHorizontal(c, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -62,7 +66,11 @@ func TestHorizontalRay(t *testing.T) {
 	t.Run("Looking directly down", func(t *testing.T) {
 		angle := PI_THREE_HALF
 		expectedX, expectedY := 7.5, 5.
-		c, _, _ := castRayHorizontal(c, angle, blockSize, m)
+		ray, err := castRayHorizontal(c, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -71,7 +79,11 @@ func TestHorizontalRay(t *testing.T) {
 	t.Run("Looking left-up", func(t *testing.T) {
 		angle := PI_HALF + PI_HALF/2
 		expectedX, expectedY := 5., 10.
-		c, _, _ := castRayHorizontal(c, angle, blockSize, m)
+		ray, err := castRayHorizontal(c, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -80,7 +92,11 @@ func TestHorizontalRay(t *testing.T) {
 	t.Run("Looking right-up", func(t *testing.T) {
 		angle := PI_HALF - PI_HALF/2
 		expectedX, expectedY := 10., 10.
-		c, _, _ := castRayHorizontal(c, angle, blockSize, m)
+		ray, err := castRayHorizontal(c, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -89,7 +105,11 @@ func TestHorizontalRay(t *testing.T) {
 	t.Run("Looking down-left", func(t *testing.T) {
 		angle := PI + PI_HALF/2
 		expectedX, expectedY := 5., 5.
-		c, _, _ := castRayHorizontal(c, angle, blockSize, m)
+		ray, err := castRayHorizontal(c, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -98,7 +118,11 @@ func TestHorizontalRay(t *testing.T) {
 	t.Run("Looking down-right", func(t *testing.T) {
 		angle := PI_THREE_HALF + PI_HALF/2
 		expectedX, expectedY := 10., 5.
-		c, _, _ := castRayHorizontal(c, angle, blockSize, m)
+		ray, err := castRayHorizontal(c, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -115,17 +139,17 @@ func TestVerticalRayAngleInvalidAngles(t *testing.T) {
 
 	t.Run("Looking directly up", func(t *testing.T) {
 		angle := PI_HALF
-		c, _, _ := castRayVertical(c, angle, blockSize, m)
-		if !c.IsInvalid() {
-			t.Fatalf("Coordinate should be invalid with angle %v, got: %v", angle, c)
+		ray, err := castRayVertical(c, angle, blockSize, m)
+		if err == nil {
+			t.Fatalf("Ray should not hit with angle %v, got: %v", angle, ray.Coord)
 		}
 	})
 
 	t.Run("Looking directly down", func(t *testing.T) {
 		angle := PI_THREE_HALF
-		c, _, _ := castRayVertical(c, angle, blockSize, m)
-		if !c.IsInvalid() {
-			t.Fatalf("Coordinate should be invalid with angle %v, got: %v", angle, c)
+		ray, err := castRayVertical(c, angle, blockSize, m)
+		if err == nil {
+			t.Fatalf("Ray should not hit with angle %v, got: %v", angle, ray.Coord)
 		}
 	})
 }
@@ -148,7 +172,11 @@ func TestVerticalRay(t *testing.T) {
 	t.Run("Looking directly left", func(t *testing.T) {
 		angle := PI
 		expectedX, expectedY := 5., 7.5
-		c, _, _ := castRayVertical(c, angle, blockSize, m)
+		ray, err := castRayVertical(c, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -157,7 +185,11 @@ func TestVerticalRay(t *testing.T) {
 	t.Run("Looking directly right", func(t *testing.T) {
 		angle := 0.
 		expectedX, expectedY := 10., 7.5
-		c, _, _ := castRayVertical(c, angle, blockSize, m)
+		ray, err := castRayVertical(c, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -166,7 +198,11 @@ func TestVerticalRay(t *testing.T) {
 	t.Run("Looking left-up", func(t *testing.T) {
 		angle := PI_HALF + PI_HALF/2
 		expectedX, expectedY := 5., 10.
-		c, _, _ := castRayVertical(c, angle, blockSize, m)
+		ray, err := castRayVertical(c, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -175,7 +211,11 @@ func TestVerticalRay(t *testing.T) {
 	t.Run("Looking right-up", func(t *testing.T) {
 		angle := PI_HALF - PI_HALF/2
 		expectedX, expectedY := 10., 10.
-		c, _, _ := castRayVertical(c, angle, blockSize, m)
+		ray, err := castRayVertical(c, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -184,7 +224,11 @@ func TestVerticalRay(t *testing.T) {
 	t.Run("Looking down-left", func(t *testing.T) {
 		angle := PI + PI_HALF/2
 		expectedX, expectedY := 5., 5.
-		c, _, _ := castRayVertical(c, angle, blockSize, m)
+		ray, err := castRayVertical(c, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -193,7 +237,11 @@ func TestVerticalRay(t *testing.T) {
 	t.Run("Looking down-right", func(t *testing.T) {
 		angle := PI_THREE_HALF + PI_HALF/2
 		expectedX, expectedY := 10., 5.
-		c, _, _ := castRayVertical(c, angle, blockSize, m)
+		ray, err := castRayVertical(c, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -216,7 +264,11 @@ func TestAsd(t *testing.T) {
 		cord := Coordinate{0, 0}
 		angle := 21.04 * DEG_TO_RAD
 		expectedX, expectedY := 13., 5.
-		c, _, _ := castRayHorizontal(cord, angle, blockSize, m)
+		ray, err := castRayHorizontal(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -226,7 +278,11 @@ func TestAsd(t *testing.T) {
 		cord := Coordinate{15, 0}
 		angle := 158.96 * DEG_TO_RAD
 		expectedX, expectedY := 2., 5.
-		c, _, _ := castRayHorizontal(cord, angle, blockSize, m)
+		ray, err := castRayHorizontal(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -236,7 +292,11 @@ func TestAsd(t *testing.T) {
 		cord := Coordinate{0, 9.99999} // if we are exactly on the line we will hit it looking down
 		angle := 338.96 * DEG_TO_RAD
 		expectedX, expectedY := 13., 5.
-		c, _, _ := castRayHorizontal(cord, angle, blockSize, m)
+		ray, err := castRayHorizontal(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -246,7 +306,11 @@ func TestAsd(t *testing.T) {
 		cord := Coordinate{15, 9.99999} // if we are exactly on the line we will hit it looking down
 		angle := 199.65 * DEG_TO_RAD
 		expectedX, expectedY := 1., 5.
-		c, _, _ := castRayHorizontal(cord, angle, blockSize, m)
+		ray, err := castRayHorizontal(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -269,7 +333,11 @@ func TestHorizontalRaySharpFlatAngles(t *testing.T) {
 		cord := Coordinate{0, 0}
 		angle := 21.04 * DEG_TO_RAD
 		expectedX, expectedY := 13., 5.
-		c, _, _ := castRayHorizontal(cord, angle, blockSize, m)
+		ray, err := castRayHorizontal(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -279,7 +347,11 @@ func TestHorizontalRaySharpFlatAngles(t *testing.T) {
 		cord := Coordinate{15, 0}
 		angle := 158.96 * DEG_TO_RAD
 		expectedX, expectedY := 2., 5.
-		c, _, _ := castRayHorizontal(cord, angle, blockSize, m)
+		ray, err := castRayHorizontal(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -289,7 +361,11 @@ func TestHorizontalRaySharpFlatAngles(t *testing.T) {
 		cord := Coordinate{0, 9.99999} // if we are exactly on the line we will hit it looking down
 		angle := 338.96 * DEG_TO_RAD
 		expectedX, expectedY := 13., 5.
-		c, _, _ := castRayHorizontal(cord, angle, blockSize, m)
+		ray, err := castRayHorizontal(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -299,7 +375,11 @@ func TestHorizontalRaySharpFlatAngles(t *testing.T) {
 		cord := Coordinate{15, 9.99999} // if we are exactly on the line we will hit it looking down
 		angle := 199.65 * DEG_TO_RAD
 		expectedX, expectedY := 1., 5.
-		c, _, _ := castRayHorizontal(cord, angle, blockSize, m)
+		ray, err := castRayHorizontal(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -323,7 +403,11 @@ func TestVerticalRaySharpFlatAngles(t *testing.T) {
 		cord := Coordinate{0, 0}
 		angle := 67.38 * DEG_TO_RAD
 		expectedX, expectedY := 5., 12.
-		c, _, _ := castRayVertical(cord, angle, blockSize, m)
+		ray, err := castRayVertical(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -333,7 +417,11 @@ func TestVerticalRaySharpFlatAngles(t *testing.T) {
 		cord := Coordinate{9.9999, 0}
 		angle := 112.62 * DEG_TO_RAD
 		expectedX, expectedY := 5., 12.
-		c, _, _ := castRayVertical(cord, angle, blockSize, m)
+		ray, err := castRayVertical(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -343,7 +431,11 @@ func TestVerticalRaySharpFlatAngles(t *testing.T) {
 		cord := Coordinate{0, 14.9999}
 		angle := 291.04 * DEG_TO_RAD
 		expectedX, expectedY := 5., 2.
-		c, _, _ := castRayVertical(cord, angle, blockSize, m)
+		ray, err := castRayVertical(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -353,7 +445,11 @@ func TestVerticalRaySharpFlatAngles(t *testing.T) {
 		cord := Coordinate{9.9999, 15}
 		angle := 248.96 * DEG_TO_RAD
 		expectedX, expectedY := 5., 2.
-		c, _, _ := castRayVertical(cord, angle, blockSize, m)
+		ray, err := castRayVertical(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c := ray.Coord
 		if !(closeTo(c.X, expectedX) && closeTo(c.Y, expectedY)) {
 			t.Fatalf("Coordinate should be (%v, %v) with %v, got: %g", expectedX, expectedY, angle, c)
 		}
@@ -382,7 +478,11 @@ func TestTheShortestRayWins(t *testing.T) {
 		cord := Coordinate{8, 6}
 		angle := 40.6 * DEG_TO_RAD
 		expectedX, expectedY := 15., 12.
-		c, d, _ := CastRay(cord, angle, blockSize, m)
+		ray, err := CastRay(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c, d := ray.Coord, ray.Dir
 		if d != VERTICAL {
 			t.Fatalf("Direction should be %v, got %v", VERTICAL.asText(), d.asText())
 		}
@@ -395,7 +495,11 @@ func TestTheShortestRayWins(t *testing.T) {
 		cord := Coordinate{8, 6}
 		angle := 56.31 * DEG_TO_RAD
 		expectedX, expectedY := 14., 15.
-		c, d, _ := CastRay(cord, angle, blockSize, m)
+		ray, err := CastRay(cord, angle, blockSize, m)
+		if err != nil {
+			t.Fatalf("Ray should hit with angle %v, got: %v", angle, err)
+		}
+		c, d := ray.Coord, ray.Dir
 		if d != HORIZONTAL {
 			t.Fatalf("Direction should be %v, got %v", HORIZONTAL.asText(), d.asText())
 		}
@@ -404,3 +508,165 @@ func TestTheShortestRayWins(t *testing.T) {
 		}
 	})
 }
+
+func TestCellsBetween(t *testing.T) {
+	blockSize := 10.
+	tests := []struct {
+		name     string
+		from, to Coordinate
+		expected []Cell
+	}{
+		{"Same point", Coordinate{15, 15}, Coordinate{15, 15}, []Cell{{1, 1}}},
+		{"Stays in one cell", Coordinate{12, 13}, Coordinate{18, 17}, []Cell{{1, 1}}},
+		{"Straight left", Coordinate{35, 15}, Coordinate{5, 15}, []Cell{{3, 1}, {2, 1}, {1, 1}, {0, 1}}},
+		{"Straight down", Coordinate{15, 35}, Coordinate{15, 5}, []Cell{{1, 3}, {1, 2}, {1, 1}, {1, 0}}},
+		{"Shallow line", Coordinate{5, 5}, Coordinate{35, 15}, []Cell{{0, 0}, {1, 0}, {1, 1}, {2, 1}, {3, 1}}},
+		// through a corner the cell above or below is included, so walls there block
+		{"Diagonal through corners", Coordinate{5, 5}, Coordinate{25, 25}, []Cell{{0, 0}, {0, 1}, {1, 1}, {1, 2}, {2, 2}}},
+		{"Negative diagonal through corners", Coordinate{25, 25}, Coordinate{5, 5}, []Cell{{2, 2}, {2, 1}, {1, 1}, {1, 0}, {0, 0}}},
+		{"Negative x, positive y", Coordinate{35, 5}, Coordinate{5, 15}, []Cell{{3, 0}, {2, 0}, {2, 1}, {1, 1}, {0, 1}}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cells := CellsBetween(tt.from, tt.to, blockSize)
+			if !reflect.DeepEqual(cells, tt.expected) {
+				t.Fatalf("Cells should be %v, got: %v", tt.expected, cells)
+			}
+		})
+	}
+}
+
+// testRoom is open, with walls around it and one in the middle
+//
+//	1 1 1 1 1
+//	1 0 0 0 1
+//	1 0 1 0 1
+//	1 0 0 0 1
+//	1 1 1 1 1
+func testRoom() [][]WallType {
+	m := make([][]WallType, 5)
+	for y := range m {
+		m[y] = make([]WallType, 5)
+		for x := range m[y] {
+			if x == 0 || y == 0 || x == 4 || y == 4 {
+				m[y][x] = 1
+			}
+		}
+	}
+	m[2][2] = 1
+	return m
+}
+
+func TestHasLineOfSight(t *testing.T) {
+	blockSize := 10.
+	m := testRoom()
+	tests := []struct {
+		name     string
+		from, to Coordinate
+		expected bool
+	}{
+		{"Same point", Coordinate{15, 15}, Coordinate{15, 15}, true},
+		{"Open line", Coordinate{15, 15}, Coordinate{35, 15}, true},
+		{"Open diagonal", Coordinate{12, 18}, Coordinate{38, 12}, true},
+		{"Diagonal through a wall", Coordinate{15, 35}, Coordinate{35, 15}, false},
+		{"Diagonal past a wall corner", Coordinate{15, 25}, Coordinate{25, 35}, true},
+		{"Blocked by a wall", Coordinate{15, 25}, Coordinate{35, 25}, false},
+		{"Blocked by a wall going left", Coordinate{35, 25}, Coordinate{15, 25}, false},
+		{"Blocked by a wall going down", Coordinate{25, 35}, Coordinate{25, 15}, false},
+		{"Ending on a wall face", Coordinate{15, 25}, Coordinate{20, 25}, true},
+		{"Ending on a wall face going left", Coordinate{35, 25}, Coordinate{30, 25}, true},
+		{"Ending on a wall face going down", Coordinate{25, 35}, Coordinate{25, 30}, true},
+		{"Ending just past a wall face", Coordinate{15, 25}, Coordinate{21, 25}, false},
+		{"Line in one cell", Coordinate{12, 12}, Coordinate{18, 17}, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if los := HasLineOfSight(tt.from, tt.to, blockSize, m); los != tt.expected {
+				t.Fatalf("Line of sight from %v to %v should be %v, got: %v", tt.from, tt.to, tt.expected, los)
+			}
+		})
+	}
+}
+
+func TestCastRays(t *testing.T) {
+	blockSize := 10.
+	m := testRoom()
+	tests := []struct {
+		name      string
+		coord     Coordinate
+		angle     float64
+		noOfRays  int
+		expected  []Coordinate
+		expectAng []float64
+	}{
+		{"One ray looks straight ahead", Coordinate{25, 15}, PI_HALF, 1, []Coordinate{{25, 20}}, []float64{PI_HALF}},
+		{"Rays spread over the fov", Coordinate{15, 15}, 0, 3, []Coordinate{{20, 10}, {40, 15}, {20, 20}}, []float64{PI_TWO - PI_HALF/2, 0, PI_HALF / 2}},
+		{"Negative angles wrap around", Coordinate{25, 32}, PI_THREE_HALF, 3, []Coordinate{{23, 30}, {25, 30}, {27, 30}}, []float64{PI + PI_HALF/2, PI_THREE_HALF, PI_TWO - PI_HALF/2}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			rays := CastRays(tt.coord, tt.angle, PI_HALF, tt.noOfRays, blockSize, m)
+			if len(rays) != tt.noOfRays {
+				t.Fatalf("There should be %d rays, got: %d", tt.noOfRays, len(rays))
+			}
+			for i, ray := range rays {
+				c := tt.expected[i]
+				if !(closeTo(ray.Coord.X, c.X) && closeTo(ray.Coord.Y, c.Y)) {
+					t.Fatalf("Ray %d should hit %v, got: %v", i, c, ray.Coord)
+				}
+				if !closeTo(ray.Ang, tt.expectAng[i]) {
+					t.Fatalf("Ray %d should have angle %v, got: %v", i, tt.expectAng[i], ray.Ang)
+				}
+			}
+		})
+	}
+
+	t.Run("Rays that hit nothing are empty", func(t *testing.T) {
+		open := [][]WallType{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}}
+		rays := CastRays(Coordinate{15, 15}, 0, PI_HALF, 3, blockSize, open)
+		for i, ray := range rays {
+			if ray != (Ray{}) {
+				t.Fatalf("Ray %d should be empty, got: %v", i, ray)
+			}
+		}
+	})
+}
+
+func TestRelativeAngle(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     float64
+		expected float64
+	}{
+		{"Same angle", 1, 1, 0},
+		{"Left of", 1.5, 1, 0.5},
+		{"Right of", 1, 1.5, -0.5},
+		{"Across zero to the left", 0.1, PI_TWO - 0.1, 0.2},
+		{"Across zero to the right", PI_TWO - 0.1, 0.1, -0.2},
+		{"Just under PI to the left", PI - 0.1, 0, PI - 0.1},
+		{"Just over PI to the left", PI + 0.1, 0, -PI + 0.1},
+		{"Just under PI to the right", 0, PI - 0.1, -PI + 0.1},
+		{"Just over PI to the right", 0, PI + 0.1, PI - 0.1},
+		{"Exactly PI", PI, 0, PI},
+		{"Exactly -PI", 0, PI, -PI},
+		{"Several turns above", 2*PI_TWO + 0.5, 0, 0.5},
+		{"Several turns below", -PI_TWO - PI - 0.5, 0, PI - 0.5},
+		{"Negative angles", -0.2, -0.5, 0.3},
+		{"Both outside", 3*PI_TWO + 0.2, -PI_TWO - 0.2, 0.4},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			d := RelativeAngle(tt.a, tt.b)
+			if !closeTo(d, tt.expected) {
+				t.Fatalf("%v relative to %v should be %v, got: %v", tt.a, tt.b, tt.expected, d)
+			}
+			if d < -PI || d > PI {
+				t.Fatalf("Relative angle should be from -PI to PI, got: %v", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package rendering
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// NO_HIGHLIGHT is the Highlight of a renderer that highlights no ray
+const NO_HIGHLIGHT = -1
+
+// RayInfo is what the debug inspector shows about a single ray
+type RayInfo struct {
+	Index int
+	Ray   raycasting.Ray
+	// Corrected is the distance with the fisheye correction, which sets the column height
+	Corrected     float64
+	TextureColumn int
+}
+
+func (info RayInfo) String() string {
+	return fmt.Sprintf("ray %d\nangle %.2f deg\ndist %.2f\ncorrected %.2f\ndir %v\nwall type %d\nhit %.2f, %.2f\ncell %d, %d\ntexture column %d",
+		info.Index, info.Ray.Ang/raycasting.DEG_TO_RAD, info.Ray.Dist, info.Corrected, info.Ray.Dir,
+		info.Ray.Wt, info.Ray.Coord.X, info.Ray.Coord.Y, info.Ray.Cell.X, info.Ray.Cell.Y, info.TextureColumn)
+}
+
+// Inspect gives the values used when rendering ray i
+func (r3d *Renderer3D) Inspect(rays []raycasting.Ray, i int) RayInfo {
+	ray := rays[i]
+	info := RayInfo{
+		Index:     i,
+		Ray:       ray,
+		Corrected: math.Cos(raycasting.NormalizeAngle(r3d.Player.Angle-ray.Ang)) * ray.Dist,
+	}
+	if t, ok := r3d.texture[uint(ray.Wt)]; ok {
+		info.TextureColumn = t.Column(ray, r3d.BlockSize)
+	}
+	return info
+}
+
+// ColumnAt is the index of the ray drawn at the screen position x, y
+func (r3d *Renderer3D) ColumnAt(x, y int) (int, bool) {
+	bounds := r3d.Screen.Bounds()
+	if x < bounds.Min.X || x >= bounds.Max.X || y < bounds.Min.Y || y >= bounds.Max.Y {
+		return 0, false
+	}
+	// columns are drawn centered on their x
+	i := int(math.Round(float64(float32(x-bounds.Min.X) / r3d.ColumnWidth)))
+	columns := int(math.Round(float64(r3d.ScreenWidth / r3d.ColumnWidth)))
+	return min(i, columns-1), true
+}
+
+// RayAt is the index of the ray closest to the screen position x, y. It is
+// only found when the position is within the field of view.
+func (r2d *Renderer2D) RayAt(x, y int, rays []raycasting.Ray) (int, bool) {
+	bounds := r2d.Screen.Bounds()
+	if len(rays) < 2 || x < bounds.Min.X || x >= bounds.Max.X || y < bounds.Min.Y || y >= bounds.Max.Y {
+		return 0, false
+	}
+	var ang float64
+	if r2d.Minimap {
+		// undo the rotation of the minimap, where the player looks up
+		dx := float64(x-bounds.Min.X) - r2d.ScreenWidth/2
+		dy := float64(y-bounds.Min.Y) - r2d.ScreenHeight/2
+		ang = raycasting.NormalizeAngle(math.Atan2(dy, dx) + math.Pi/2 + r2d.player.Angle)
+	} else {
+		wx := float64(x-bounds.Min.X) / r2d.UnitX
+		wy := float64(y-bounds.Min.Y) / r2d.UnitY
+		ang = raycasting.NormalizeAngle(math.Atan2(wy-r2d.player.Coord.Y, wx-r2d.player.Coord.X))
+	}
+	best, bestDiff := 0, math.Inf(1)
+	for i, ray := range rays {
+		if diff := math.Abs(raycasting.RelativeAngle(ray.Ang, ang)); diff < bestDiff {
+			best, bestDiff = i, diff
+		}
+	}
+	spacing := math.Abs(raycasting.RelativeAngle(rays[0].Ang, rays[1].Ang))
+	return best, bestDiff <= spacing
+}
+
+// rayColor is the color and width to draw ray i with
+func (r2d *Renderer2D) rayColor(i int, ray raycasting.Ray, maxDist float64, def color.Color) (color.Color, float32) {
+	if i == r2d.Highlight {
+		return r2d.HighlightColor, 3
+	}
+	if !r2d.ColorRays {
+		return def, 1
+	}
+	clr := r2d.HorizontalRayColor
+	if ray.Dir == raycasting.VERTICAL {
+		clr = r2d.VerticalRayColor
+	}
+	// far rays fade to a third of their color
+	f := 1 - 2*min(ray.Dist/maxDist, 1)/3
+	return color.RGBA{uint8(float64(clr.R) * f), uint8(float64(clr.G) * f), uint8(float64(clr.B) * f), 255}, 1
+}
+
+func maxDist(rays []raycasting.Ray) float64 {
+	res := 1.
+	for _, ray := range rays {
+		res = max(res, ray.Dist)
+	}
+	return res
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hvassaa/gaster/automap"
 	"github.com/hvassaa/gaster/player"
 	"github.com/hvassaa/gaster/raycasting"
 )
@@ -17,6 +18,38 @@ type Renderer2D struct {
 	player                                           *player.Player
 	PlayerColor, RayColor, DirectionColor, WallColor color.Color
 	mab                                              [][]raycasting.WallType
+	// Minimap centers the view on the player and rotates it with the player,
+	// instead of showing the whole world
+	Minimap bool
+	// Zoom scales the minimap, at 1 it shows MINIMAP_BLOCKS blocks across
+	Zoom            float64
+	Frame           MinimapFrame
+	offscreen, mask *ebiten.Image
+	maskFrame       MinimapFrame
+	// Explored hides or dims the cells that have not been seen, or is nil to show everything
+	Explored *automap.Explored
+	// HideUnexplored hides unexplored cells completely, instead of dimming them
+	HideUnexplored                   bool
+	UnexploredColor, DimmedWallColor color.Color
+	// Highlight is the index of a ray to draw thicker in HighlightColor, or NO_HIGHLIGHT
+	Highlight      int
+	HighlightColor color.Color
+	// ColorRays colors rays by the side of the wall they hit, fading with distance
+	ColorRays                            bool
+	HorizontalRayColor, VerticalRayColor color.RGBA
+	// Sprites are drawn as dots in their color
+	Sprites []Sprite
+}
+
+// cellColor is the color to fill a map cell with, if any
+func (r2d *Renderer2D) cellColor(x, y int, wallType raycasting.WallType) (color.Color, bool) {
+	if r2d.Explored == nil || r2d.Explored.Seen(x, y) {
+		return r2d.WallColor, wallType != 0
+	}
+	if wallType != 0 && !r2d.HideUnexplored {
+		return r2d.DimmedWallColor, true
+	}
+	return r2d.UnexploredColor, true
 }
 
 func (r2d *Renderer2D) translateX(screen *ebiten.Image, x float64) float32 {
@@ -29,33 +62,47 @@ func (r2d *Renderer2D) translateY(screen *ebiten.Image, y float64) float32 {
 
 func NewRenderer2D(screen *ebiten.Image, worldWidth, worldHeight, blockSize float64, player *player.Player, mab [][]raycasting.WallType) *Renderer2D {
 	return &Renderer2D{
-		UnitX:          float64(screen.Bounds().Dx()) / worldWidth,
-		UnitY:          float64(screen.Bounds().Dy()) / worldHeight,
-		Screen:         screen,
-		ScreenWidth:    float64(screen.Bounds().Dx()),
-		ScreenHeight:   float64(screen.Bounds().Dy()),
-		BlockSize:      blockSize,
-		PlayerColor:    color.RGBA{200, 0, 0, 255},
-		RayColor:       color.RGBA{0, 200, 200, 255},
-		DirectionColor: color.RGBA{0, 200, 0, 255},
-		WallColor:      color.RGBA{0, 50, 50, 255},
-		player:         player,
-		mab:            mab,
+		UnitX:           float64(screen.Bounds().Dx()) / worldWidth,
+		UnitY:           float64(screen.Bounds().Dy()) / worldHeight,
+		Screen:          screen,
+		ScreenWidth:     float64(screen.Bounds().Dx()),
+		ScreenHeight:    float64(screen.Bounds().Dy()),
+		BlockSize:       blockSize,
+		PlayerColor:     color.RGBA{200, 0, 0, 255},
+		RayColor:        color.RGBA{0, 200, 200, 255},
+		DirectionColor:  color.RGBA{0, 200, 0, 255},
+		WallColor:       color.RGBA{0, 50, 50, 255},
+		player:          player,
+		mab:             mab,
+		Zoom:            1,
+		UnexploredColor: color.RGBA{20, 20, 20, 255},
+		DimmedWallColor: color.RGBA{0, 25, 25, 255},
+		Highlight:       NO_HIGHLIGHT,
+		HighlightColor:  color.RGBA{255, 255, 0, 255},
+
+		HorizontalRayColor: color.RGBA{255, 120, 40, 255},
+		VerticalRayColor:   color.RGBA{60, 140, 255, 255},
 	}
 }
 
 func (r2d *Renderer2D) Render(rays []raycasting.Ray) {
+	if r2d.Minimap {
+		r2d.renderMinimap(rays)
+		return
+	}
 	screen := r2d.Screen
 	screen.Fill(color.Black)
-	xBlockWidth := r2d.translateX(screen, r2d.BlockSize)
-	yBlockWidth := r2d.translateY(screen, r2d.BlockSize)
+	xBlockWidth := float32(r2d.BlockSize * r2d.UnitX)
+	yBlockWidth := float32(r2d.BlockSize * r2d.UnitY)
+	minX := float32(screen.Bounds().Min.X)
+	minY := float32(screen.Bounds().Min.Y)
 
 	for y, yv := range r2d.mab {
 		yp := r2d.translateY(screen, float64(y)*r2d.BlockSize)
 		for x, wallType := range yv {
 			xp := r2d.translateX(screen, float64(x)*r2d.BlockSize)
-			if wallType != 0 {
-				vector.DrawFilledRect(screen, xp, yp, xBlockWidth, yBlockWidth, r2d.WallColor, false)
+			if clr, ok := r2d.cellColor(x, y, wallType); ok {
+				vector.DrawFilledRect(screen, xp, yp, xBlockWidth, yBlockWidth, clr, false)
 			}
 		}
 	}
@@ -65,10 +112,10 @@ func (r2d *Renderer2D) Render(rays []raycasting.Ray) {
 		for x := range yv {
 			xp := r2d.translateX(screen, float64(x)*r2d.BlockSize)
 			if y == 0 {
-				vector.StrokeLine(screen, xp, 0, xp, float32(r2d.ScreenHeight), 1, color.RGBA{70, 10, 10, 255}, false)
+				vector.StrokeLine(screen, xp, minY, xp, minY+float32(r2d.ScreenHeight), 1, color.RGBA{70, 10, 10, 255}, false)
 			}
 		}
-		vector.StrokeLine(screen, 0, yp, float32(r2d.ScreenWidth), yp, 1, color.RGBA{70, 10, 10, 255}, false)
+		vector.StrokeLine(screen, minX, yp, minX+float32(r2d.ScreenWidth), yp, 1, color.RGBA{70, 10, 10, 255}, false)
 	}
 
 	radius := 20 * (r2d.UnitX + r2d.UnitY) / 2
@@ -76,13 +123,18 @@ func (r2d *Renderer2D) Render(rays []raycasting.Ray) {
 	playerX := r2d.translateX(screen, float64(r2d.player.Coord.X))
 	playerY := r2d.translateY(screen, float64(r2d.player.Coord.Y))
 	vector.DrawFilledCircle(screen, playerX, playerY, float32(radius), r2d.PlayerColor, false)
-	for _, ray := range rays {
+	farthest := maxDist(rays)
+	for i, ray := range rays {
 		x1 := r2d.translateX(screen, r2d.player.Coord.X)
 		y1 := r2d.translateY(screen, r2d.player.Coord.Y)
 		x2 := r2d.translateX(screen, ray.Coord.X)
 		y2 := r2d.translateY(screen, ray.Coord.Y)
-		vector.StrokeLine(screen, x1, y1, x2, y2, 1, r2d.PlayerColor, false)
+		clr, width := r2d.rayColor(i, ray, farthest, r2d.PlayerColor)
+		vector.StrokeLine(screen, x1, y1, x2, y2, width, clr, false)
 	}
+	r2d.renderSpriteDots(screen, func(x, y float64) (float32, float32) {
+		return r2d.translateX(screen, x), r2d.translateY(screen, y)
+	}, float32(radius))
 	directionRayX := r2d.translateX(screen, r2d.player.Coord.X+math.Cos(r2d.player.Angle)*radius*10)
 	directionRayY := r2d.translateY(screen, r2d.player.Coord.Y+math.Sin(r2d.player.Angle)*radius*10)
 	vector.StrokeLine(screen, playerX, playerY, directionRayX, directionRayY, 1, r2d.DirectionColor, false)
@@ -1,11 +1,13 @@
 package rendering
 
 import (
+	"image"
 	"image/color"
 	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hvassaa/gaster/lighting"
 	"github.com/hvassaa/gaster/player"
 	"github.com/hvassaa/gaster/raycasting"
 )
@@ -18,8 +20,28 @@ type Renderer3D struct {
 	ScreenMid, ColumnWidth, ScreenWidth, ScreenHeight float32
 	texture                                           map[uint]Texture
 	Player                                            *player.Player
+	Lighting                                          lighting.Config
+	LightingZones                                     []lighting.Zone
+	// Lights are point lights, or nil to only use Lighting
+	Lights *lighting.Lights
+	// Sky is a panorama shown instead of TopColor above outdoor cells, or nil for no sky
+	Sky *ebiten.Image
+	// Outdoor marks the cells without ceiling, indexed like the map.
+	// If it is nil while Sky is set, every cell is outdoor.
+	Outdoor [][]bool
+	// Highlight is the index of a ray to outline in HighlightColor, or NO_HIGHLIGHT
+	Highlight      int
+	HighlightColor color.Color
+	// FOV is the field of view the rays were cast with, in radians, to know where sprites are
+	FOV     float64
+	Sprites []Sprite
+	// Gun is drawn in front of everything, or nil for no gun
+	Gun *Gun
 }
 
+// FLOOR_STEP is the height in pixels of each separately lit floor segment
+const FLOOR_STEP = 6
+
 func NewRenderer3D(screen *ebiten.Image, player *player.Player, noOfRays int, blockSize float64) *Renderer3D {
 	wallColors := make(map[raycasting.WallType]color.Color)
 	wallColors[0] = color.RGBA{255, 0, 0, 255}
@@ -29,16 +51,20 @@ func NewRenderer3D(screen *ebiten.Image, player *player.Player, noOfRays int, bl
 	screenWidth := float32(screen.Bounds().Size().X)
 
 	return &Renderer3D{
-		TopColor:     color.RGBA{50, 150, 150, 255},
-		BottomColor:  color.RGBA{200, 200, 200, 255},
-		BlockSize:    blockSize,
-		WallColors:   wallColors,
-		Screen:       screen,
-		ScreenHeight: screenHeight,
-		ScreenWidth:  screenWidth,
-		ScreenMid:    screenHeight / 2.,
-		ColumnWidth:  screenWidth / float32(noOfRays),
-		Player:       player,
+		TopColor:       color.RGBA{50, 150, 150, 255},
+		BottomColor:    color.RGBA{200, 200, 200, 255},
+		BlockSize:      blockSize,
+		WallColors:     wallColors,
+		Screen:         screen,
+		ScreenHeight:   screenHeight,
+		ScreenWidth:    screenWidth,
+		ScreenMid:      screenHeight / 2.,
+		ColumnWidth:    screenWidth / float32(noOfRays),
+		Player:         player,
+		Lighting:       lighting.Default(),
+		Highlight:      NO_HIGHLIGHT,
+		HighlightColor: color.RGBA{255, 255, 0, 255},
+		FOV:            60 * raycasting.DEG_TO_RAD,
 		texture: map[uint]Texture{
 			1: LoadTexture(CROSS_TEXTURE),
 			2: LoadTexture(ASD),
@@ -46,56 +72,160 @@ func NewRenderer3D(screen *ebiten.Image, player *player.Player, noOfRays int, bl
 	}
 }
 
+// screenTop is the y coordinate of the top of the screen, which is not 0 for sub images
+func (r3d *Renderer3D) screenTop() float32 {
+	return float32(r3d.Screen.Bounds().Min.Y)
+}
+
+func (r3d *Renderer3D) screenBottom() float32 {
+	return r3d.screenTop() + r3d.ScreenHeight
+}
+
+// flatAt is the distance along ray to the floor or ceiling seen at screen row y, and the point seen there
+func (r3d *Renderer3D) flatAt(y, renderMiddle float32, ray raycasting.Ray) (float64, raycasting.Coordinate) {
+	noFish := math.Cos(raycasting.NormalizeAngle(r3d.Player.Angle - ray.Ang))
+	// the inverse of the column height calculation
+	perpDist := r3d.BlockSize * float64(r3d.ScreenHeight) / (2 * math.Abs(float64(y-renderMiddle)))
+	dist := perpDist / noFish
+	return dist, raycasting.Coordinate{
+		X: r3d.Player.Coord.X + math.Cos(ray.Ang)*dist,
+		Y: r3d.Player.Coord.Y + math.Sin(ray.Ang)*dist,
+	}
+}
+
+// shadeFlat fogs clr by dist, and lights it by the floor cell at c if there are lights
+func (r3d *Renderer3D) shadeFlat(clr color.Color, dist float64, c raycasting.Coordinate, light lighting.Config) color.RGBA {
+	base := color.RGBAModel.Convert(clr).(color.RGBA)
+	if r3d.Lights == nil {
+		return light.ShadeFlat(base, dist)
+	}
+	return light.ShadeFlatLit(base, dist, r3d.Lights.Floor(c))
+}
+
+// renderFloor draws the floor below a column in segments, each shaded by the floor cell it shows
+func (r3d *Renderer3D) renderFloor(x, bot, renderMiddle float32, ray raycasting.Ray, light lighting.Config) {
+	for y := max(bot, renderMiddle+1); y < r3d.screenBottom(); y += FLOOR_STEP {
+		dist, c := r3d.flatAt(y+FLOOR_STEP/2, renderMiddle, ray)
+		clr := r3d.shadeFlat(r3d.BottomColor, dist, c, light)
+		vector.StrokeLine(r3d.Screen, x, y, x, min(y+FLOOR_STEP, r3d.screenBottom()), r3d.ColumnWidth, clr, false)
+	}
+}
+
+// renderCeiling is renderFloor mirrored, for the ceiling above a column
+func (r3d *Renderer3D) renderCeiling(x, top, renderMiddle float32, ray raycasting.Ray, light lighting.Config) {
+	for y := min(top, renderMiddle-1); y > r3d.screenTop(); y -= FLOOR_STEP {
+		dist, c := r3d.flatAt(y-FLOOR_STEP/2, renderMiddle, ray)
+		clr := r3d.shadeFlat(r3d.TopColor, dist, c, light)
+		vector.StrokeLine(r3d.Screen, x, max(y-FLOOR_STEP, r3d.screenTop()), x, y, r3d.ColumnWidth, clr, false)
+	}
+}
+
+func (r3d *Renderer3D) isOutdoor(c raycasting.Coordinate) bool {
+	if r3d.Outdoor == nil {
+		return true
+	}
+	x := int(math.Floor(c.X / r3d.BlockSize))
+	y := int(math.Floor(c.Y / r3d.BlockSize))
+	if y < 0 || y >= len(r3d.Outdoor) || x < 0 || x >= len(r3d.Outdoor[y]) {
+		return false
+	}
+	return r3d.Outdoor[y][x]
+}
+
+// renderSky draws the part of the sky panorama seen by a column, and then
+// covers the ceiling above indoor cells again
+func (r3d *Renderer3D) renderSky(x, top, renderMiddle float32, ray raycasting.Ray, columnAngle float64, light lighting.Config) {
+	if top <= r3d.screenTop() {
+		return
+	}
+	skyWidth := r3d.Sky.Bounds().Dx()
+	skyHeight := r3d.Sky.Bounds().Dy()
+	sx := int(ray.Ang/raycasting.PI_TWO*float64(skyWidth)) % skyWidth
+	sw := max(int(columnAngle/raycasting.PI_TWO*float64(skyWidth)), 1)
+	// we do not wrap around the seam, we just show a slightly thinner slice
+	sw = min(sw, skyWidth-sx)
+
+	// the sky is two screens tall, with the horizon following renderMiddle
+	scaleY := 2 * float64(r3d.ScreenHeight) / float64(skyHeight)
+	left := x - r3d.ColumnWidth/2
+	bounds := r3d.Screen.Bounds()
+	dst := r3d.Screen.SubImage(image.Rect(int(left), bounds.Min.Y, int(math.Ceil(float64(left+r3d.ColumnWidth))), int(top))).(*ebiten.Image)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(r3d.ColumnWidth)/float64(sw), scaleY)
+	op.GeoM.Translate(float64(left), float64(renderMiddle)-float64(skyHeight)/2*scaleY)
+	dst.DrawImage(r3d.Sky.SubImage(image.Rect(sx, 0, sx+sw, skyHeight)).(*ebiten.Image), op)
+
+	if r3d.Outdoor == nil {
+		return
+	}
+	for y := min(top, renderMiddle-1); y > r3d.screenTop(); y -= FLOOR_STEP {
+		dist, c := r3d.flatAt(y-FLOOR_STEP/2, renderMiddle, ray)
+		if r3d.isOutdoor(c) {
+			continue
+		}
+		clr := r3d.shadeFlat(r3d.TopColor, dist, c, light)
+		vector.StrokeLine(r3d.Screen, x, max(y-FLOOR_STEP, r3d.screenTop()), x, y, r3d.ColumnWidth, clr, false)
+	}
+}
+
 func (r3d *Renderer3D) Render(rays []raycasting.Ray) {
 	xStart := r3d.Screen.Bounds().Min.X
 	// we render walls "half up and down" from this point
 	// we initially set it to the middle of the screen
-	renderMiddle := r3d.ScreenMid + float32(r3d.Player.HozAngle)*r3d.ScreenHeight*3/180
+	renderMiddle := r3d.screenTop() + r3d.ScreenMid + float32(r3d.Player.HozAngle)*r3d.ScreenHeight*3/180
+	light := lighting.ConfigAt(r3d.LightingZones, r3d.Lighting, *r3d.Player.Coord, r3d.BlockSize)
+	topColor := light.ShadeFlat(color.RGBAModel.Convert(r3d.TopColor).(color.RGBA), 0)
+	bottomColor := light.ShadeFlat(color.RGBAModel.Convert(r3d.BottomColor).(color.RGBA), 0)
+	// the angle each column covers, to know how much of the sky to show per column
+	var skyColumnAngle float64
+	if len(rays) > 1 {
+		skyColumnAngle = raycasting.NormalizeAngle(rays[len(rays)-1].Ang-rays[0].Ang) / float64(len(rays)-1)
+	}
 
 	for i, ray := range rays {
 		columnColor := color.RGBA{0, 0, 0, 255}
 		b := r3d.texture[uint(rays[i].Wt)]
-		coord := ray.Coord
-		var xPosOnBlock float64
-		if ray.Dir == raycasting.HORIZONTAL {
-			xPosOnBlock = math.Mod(coord.X, r3d.BlockSize)
-			columnColor.R = 50
-		} else {
-			xPosOnBlock = math.Mod(coord.Y, r3d.BlockSize)
-		}
 
 		// this avoid fisheye
 		noFish := math.Cos(raycasting.NormalizeAngle(r3d.Player.Angle-ray.Ang)) * ray.Dist
+		face := lighting.FaceOf(ray)
+		var wallLight lighting.RGB
+		if r3d.Lights != nil {
+			wallLight = r3d.Lights.Wall(ray)
+		}
 		columnHeight := float32((r3d.BlockSize * float64(r3d.ScreenHeight)) / noFish)
 
 		x := float32(xStart) + float32(i)*r3d.ColumnWidth
 		yTextureListSize := len(b)
-		xTextureListSize := len(b[0])
-		xTextureSliceSize := r3d.BlockSize / float64(xTextureListSize)
-		xTextureIdx := int(math.Floor(xPosOnBlock / xTextureSliceSize))
+		xTextureIdx := b.Column(ray, r3d.BlockSize)
 		top := renderMiddle - columnHeight/2
 		bot := renderMiddle + columnHeight/2
 		vertSlice := columnHeight / float32(yTextureListSize)
 
-		// draw top and bottom colors
-		vector.StrokeLine(r3d.Screen, x, bot, x, r3d.ScreenHeight, r3d.ColumnWidth, r3d.BottomColor, false)
-		vector.StrokeLine(r3d.Screen, x, top, x, 0, r3d.ColumnWidth, r3d.TopColor, false)
+		// draw top and bottom colors, in one line each unless they change with the distance
+		if r3d.Lights == nil && light.FogCurve == lighting.FOG_NONE {
+			vector.StrokeLine(r3d.Screen, x, bot, x, r3d.screenBottom(), r3d.ColumnWidth, bottomColor, false)
+			vector.StrokeLine(r3d.Screen, x, top, x, r3d.screenTop(), r3d.ColumnWidth, topColor, false)
+		} else {
+			r3d.renderFloor(x, bot, renderMiddle, ray, light)
+			r3d.renderCeiling(x, top, renderMiddle, ray, light)
+		}
+		if r3d.Sky != nil {
+			r3d.renderSky(x, top, renderMiddle, ray, skyColumnAngle, light)
+		}
 
 		for j := 0; j < yTextureListSize; j++ {
 			fj := float64(j)
 			var y1 float32 = top + vertSlice*float32(fj)
 			var y2 float32 = y1 + vertSlice
 			yTextureIdx := j
-			angle := ray.Ang
-			leftWallHit := angle > raycasting.PI_HALF && angle < raycasting.PI_THREE_HALF && ray.Dir == raycasting.VERTICAL
-			bottomWallHit := angle < raycasting.PI && ray.Dir == raycasting.HORIZONTAL
-			if leftWallHit || bottomWallHit {
-				// when the way is left or down, the texture is x-mirrored
-				columnColor.B = b[yTextureIdx][xTextureListSize-xTextureIdx-1]
-			} else {
-				columnColor.B = b[yTextureIdx][xTextureIdx]
-			}
-			vector.StrokeLine(r3d.Screen, x, y1, x, y2, r3d.ColumnWidth, columnColor, false)
+			columnColor.B = b[yTextureIdx][xTextureIdx]
+			vector.StrokeLine(r3d.Screen, x, y1, x, y2, r3d.ColumnWidth, light.ShadeLit(columnColor, face, ray.Dist, wallLight), false)
+		}
+
+		if i == r3d.Highlight {
+			left := x - r3d.ColumnWidth/2
+			vector.StrokeRect(r3d.Screen, left, max(top, r3d.screenTop()), r3d.ColumnWidth, min(bot, r3d.screenBottom())-max(top, r3d.screenTop()), 2, r3d.HighlightColor, false)
 		}
 
 		// for y := int(math.Round(float64(bot))); y < r3d.Screen.Bounds().Size().Y; y++ {
@@ -117,6 +247,10 @@ func (r3d *Renderer3D) Render(rays []raycasting.Ray) {
 		// 	vector.StrokeLine(r3d.Screen, x, bot, x+float32(math.Cos(ray.Ang))*columnHeight, r3d.ScreenHeight, r3d.ColumnWidth, topColor, false)
 		// }
 	}
+	r3d.renderSprites(rays, renderMiddle, light)
+	if r3d.Gun != nil {
+		r3d.renderGun(light)
+	}
 
 	// for i, ray := range rays {
 	// 	// this avoid fisheye on "right ahead walls"
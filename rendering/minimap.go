@@ -0,0 +1,166 @@
+package rendering
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+type MinimapFrame int
+
+const (
+	FRAME_SQUARE MinimapFrame = iota
+	FRAME_CIRCLE
+)
+
+// MINIMAP_BLOCKS is how many blocks fit across the minimap at zoom 1
+const MINIMAP_BLOCKS = 16
+
+var (
+	whiteImage    = ebiten.NewImage(3, 3)
+	whiteSubImage = whiteImage.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+)
+
+func init() {
+	whiteImage.Fill(color.White)
+}
+
+// fillPolygon fills the polygon with corners xs, ys
+func fillPolygon(dst *ebiten.Image, xs, ys []float32, clr color.Color) {
+	var path vector.Path
+	path.MoveTo(xs[0], ys[0])
+	for i := 1; i < len(xs); i++ {
+		path.LineTo(xs[i], ys[i])
+	}
+	path.Close()
+	vs, is := path.AppendVerticesAndIndicesForFilling(nil, nil)
+	r, g, b, a := clr.RGBA()
+	for i := range vs {
+		vs[i].SrcX = 1
+		vs[i].SrcY = 1
+		vs[i].ColorR = float32(r) / 0xffff
+		vs[i].ColorG = float32(g) / 0xffff
+		vs[i].ColorB = float32(b) / 0xffff
+		vs[i].ColorA = float32(a) / 0xffff
+	}
+	op := &ebiten.DrawTrianglesOptions{}
+	op.ColorScaleMode = ebiten.ColorScaleModePremultipliedAlpha
+	dst.DrawTriangles(vs, is, whiteSubImage, op)
+}
+
+// minimapTransform returns a function from world coordinates to minimap pixels,
+// with the player in the middle looking up
+func (r2d *Renderer2D) minimapTransform() func(x, y float64) (float32, float32) {
+	size := min(r2d.ScreenWidth, r2d.ScreenHeight)
+	scale := size / (r2d.BlockSize * MINIMAP_BLOCKS) * r2d.Zoom
+	// rotate so the player's angle points up, which is -PI/2 on screen
+	rot := -r2d.player.Angle - math.Pi/2
+	sin, cos := math.Sin(rot), math.Cos(rot)
+	cx, cy := r2d.ScreenWidth/2, r2d.ScreenHeight/2
+	px, py := r2d.player.Coord.X, r2d.player.Coord.Y
+	return func(x, y float64) (float32, float32) {
+		dx, dy := x-px, y-py
+		rx := dx*cos - dy*sin
+		ry := dx*sin + dy*cos
+		return float32(cx + rx*scale), float32(cy + ry*scale)
+	}
+}
+
+func (r2d *Renderer2D) renderMinimap(rays []raycasting.Ray) {
+	w, h := int(r2d.ScreenWidth), int(r2d.ScreenHeight)
+	if r2d.offscreen == nil || r2d.offscreen.Bounds().Dx() != w || r2d.offscreen.Bounds().Dy() != h || r2d.maskFrame != r2d.Frame {
+		r2d.maskFrame = r2d.Frame
+		r2d.offscreen = ebiten.NewImage(w, h)
+		r2d.mask = ebiten.NewImage(w, h)
+		if r2d.Frame == FRAME_CIRCLE {
+			vector.DrawFilledCircle(r2d.mask, float32(w)/2, float32(h)/2, float32(min(w, h))/2, color.White, true)
+		} else {
+			r2d.mask.Fill(color.White)
+		}
+	}
+	dst := r2d.offscreen
+	dst.Fill(color.RGBA{10, 10, 10, 230})
+	transform := r2d.minimapTransform()
+
+	// only walls within the frame are drawn, the diagonal of the frame is at most
+	// sqrt(2) times the view, and we add a block of margin
+	size := min(r2d.ScreenWidth, r2d.ScreenHeight)
+	scale := size / (r2d.BlockSize * MINIMAP_BLOCKS) * r2d.Zoom
+	reach := math.Max(r2d.ScreenWidth, r2d.ScreenHeight)/scale*math.Sqrt2/2 + r2d.BlockSize
+	minX := int(math.Floor((r2d.player.Coord.X - reach) / r2d.BlockSize))
+	maxX := int(math.Ceil((r2d.player.Coord.X + reach) / r2d.BlockSize))
+	minY := int(math.Floor((r2d.player.Coord.Y - reach) / r2d.BlockSize))
+	maxY := int(math.Ceil((r2d.player.Coord.Y + reach) / r2d.BlockSize))
+	xs, ys := make([]float32, 4), make([]float32, 4)
+	for y := max(minY, 0); y < min(maxY, len(r2d.mab)); y++ {
+		for x := max(minX, 0); x < min(maxX, len(r2d.mab[y])); x++ {
+			clr, ok := r2d.cellColor(x, y, r2d.mab[y][x])
+			if !ok {
+				continue
+			}
+			x0, y0 := float64(x)*r2d.BlockSize, float64(y)*r2d.BlockSize
+			x1, y1 := x0+r2d.BlockSize, y0+r2d.BlockSize
+			xs[0], ys[0] = transform(x0, y0)
+			xs[1], ys[1] = transform(x1, y0)
+			xs[2], ys[2] = transform(x1, y1)
+			xs[3], ys[3] = transform(x0, y1)
+			fillPolygon(dst, xs, ys, clr)
+		}
+	}
+
+	cx, cy := float32(w)/2, float32(h)/2
+	farthest := maxDist(rays)
+	for i, ray := range rays {
+		x2, y2 := transform(ray.Coord.X, ray.Coord.Y)
+		clr, width := r2d.rayColor(i, ray, farthest, r2d.RayColor)
+		vector.StrokeLine(dst, cx, cy, x2, y2, width, clr, false)
+	}
+	radius := float32(r2d.BlockSize / 4 * scale)
+	r2d.renderSpriteDots(dst, transform, radius*2)
+	vector.DrawFilledCircle(dst, cx, cy, max(radius, 2), r2d.PlayerColor, true)
+	vector.StrokeLine(dst, cx, cy, cx, cy-float32(size)/6, 1, r2d.DirectionColor, false)
+
+	// clip everything outside the frame
+	op := &ebiten.DrawImageOptions{}
+	op.Blend = ebiten.BlendDestinationIn
+	dst.DrawImage(r2d.mask, op)
+	r2d.renderCompass(dst, transform)
+
+	if r2d.Frame == FRAME_CIRCLE {
+		vector.StrokeCircle(dst, cx, cy, float32(min(w, h))/2-1, 2, r2d.DirectionColor, true)
+	} else {
+		vector.StrokeRect(dst, 1, 1, float32(w)-2, float32(h)-2, 2, r2d.DirectionColor, false)
+	}
+
+	op = &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(r2d.Screen.Bounds().Min.X), float64(r2d.Screen.Bounds().Min.Y))
+	r2d.Screen.DrawImage(dst, op)
+}
+
+// renderCompass draws a needle in the top right corner, with the red end pointing north
+func (r2d *Renderer2D) renderCompass(dst *ebiten.Image, transform func(x, y float64) (float32, float32)) {
+	w, h := float32(r2d.ScreenWidth), float32(r2d.ScreenHeight)
+	size := min(w, h) / 12
+	cx, cy := w-size*1.6, size*1.6
+	if r2d.Frame == FRAME_CIRCLE {
+		// keep the compass inside the circle
+		cx, cy = w/2+min(w, h)*0.33, h/2-min(w, h)*0.33
+	}
+	// north is negative y, so find where it points after the rotation
+	ox, oy := transform(r2d.player.Coord.X, r2d.player.Coord.Y)
+	nx, ny := transform(r2d.player.Coord.X, r2d.player.Coord.Y-1)
+	dx, dy := nx-ox, ny-oy
+	l := float32(math.Hypot(float64(dx), float64(dy)))
+	dx, dy = dx/l*size, dy/l*size
+	// the needle is two triangles, with its sides perpendicular to the direction
+	px, py := -dy/4, dx/4
+
+	vector.DrawFilledCircle(dst, cx, cy, size*1.2, color.RGBA{0, 0, 0, 200}, true)
+	fillPolygon(dst, []float32{cx + dx, cx + px, cx - px}, []float32{cy + dy, cy + py, cy - py}, color.RGBA{220, 30, 30, 255})
+	fillPolygon(dst, []float32{cx - dx, cx + px, cx - px}, []float32{cy - dy, cy + py, cy - py}, color.RGBA{230, 230, 230, 255})
+	vector.StrokeCircle(dst, cx, cy, size*1.2, 1, color.RGBA{230, 230, 230, 255}, true)
+}
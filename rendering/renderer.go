@@ -1,7 +1,18 @@
 package rendering
 
-import "github.com/hvassaa/gaster/raycasting"
+import (
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/rendering/software"
+	"github.com/hvassaa/gaster/rendering/terminal"
+)
 
 type Renderer interface {
 	Render(rays []raycasting.Ray)
 }
+
+// the renderers without ebiten live in their own packages, so they can be
+// used without a window, but they are still Renderers
+var (
+	_ Renderer = (*software.Renderer3D)(nil)
+	_ Renderer = (*terminal.Renderer)(nil)
+)
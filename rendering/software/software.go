@@ -0,0 +1,148 @@
+// Package software renders the first person view on the CPU into an
+// image.RGBA, without ebiten, so frames can be rendered without a window.
+package software
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hvassaa/gaster/lighting"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/texture"
+)
+
+// Renderer3D draws the same view as rendering.Renderer3D, pixel by pixel.
+// It implements rendering.Renderer.
+type Renderer3D struct {
+	TopColor, BottomColor color.RGBA
+	BlockSize             float64
+	Image                 *image.RGBA
+	Player                *player.Player
+	Textures              map[raycasting.WallType]texture.Texture
+	Lighting              lighting.Config
+	LightingZones         []lighting.Zone
+	// Lights are point lights, or nil to only use Lighting
+	Lights *lighting.Lights
+	// Sky is a panorama shown above outdoor cells, or nil for no sky
+	Sky image.Image
+	// Outdoor marks the cells without ceiling. If it is nil while Sky is set, every cell is outdoor.
+	Outdoor [][]bool
+}
+
+func NewRenderer3D(img *image.RGBA, player *player.Player, blockSize float64, textures map[raycasting.WallType]texture.Texture) *Renderer3D {
+	return &Renderer3D{
+		TopColor:    color.RGBA{50, 150, 150, 255},
+		BottomColor: color.RGBA{200, 200, 200, 255},
+		BlockSize:   blockSize,
+		Image:       img,
+		Player:      player,
+		Textures:    textures,
+		Lighting:    lighting.Default(),
+	}
+}
+
+// Frame renders a single frame of the given size, with default colors and lighting
+func Frame(width, height int, p *player.Player, rays []raycasting.Ray, blockSize float64, textures map[raycasting.WallType]texture.Texture) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	NewRenderer3D(img, p, blockSize, textures).Render(rays)
+	return img
+}
+
+func (r *Renderer3D) isOutdoor(c raycasting.Coordinate) bool {
+	if r.Outdoor == nil {
+		return true
+	}
+	x := int(math.Floor(c.X / r.BlockSize))
+	y := int(math.Floor(c.Y / r.BlockSize))
+	if y < 0 || y >= len(r.Outdoor) || x < 0 || x >= len(r.Outdoor[y]) {
+		return false
+	}
+	return r.Outdoor[y][x]
+}
+
+func (r *Renderer3D) skyAt(angle, y, renderMiddle, screenHeight float64) color.RGBA {
+	b := r.Sky.Bounds()
+	// the sky is two screens tall, with the horizon following renderMiddle
+	scaleY := 2 * screenHeight / float64(b.Dy())
+	sx := int(angle/raycasting.PI_TWO*float64(b.Dx())) % b.Dx()
+	sy := min(max(int((y-renderMiddle)/scaleY)+b.Dy()/2, 0), b.Dy()-1)
+	return color.RGBAModel.Convert(r.Sky.At(b.Min.X+sx, b.Min.Y+sy)).(color.RGBA)
+}
+
+func (r *Renderer3D) Render(rays []raycasting.Ray) {
+	if len(rays) == 0 {
+		return
+	}
+	bounds := r.Image.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	screenHeight := float64(height)
+	// we render walls "half up and down" from this point
+	renderMiddle := screenHeight/2 + r.Player.HozAngle*screenHeight*3/180
+	light := lighting.ConfigAt(r.LightingZones, r.Lighting, *r.Player.Coord, r.BlockSize)
+	var columnAngle float64
+	if len(rays) > 1 {
+		columnAngle = raycasting.NormalizeAngle(rays[len(rays)-1].Ang-rays[0].Ang) / float64(len(rays)-1)
+	}
+
+	for sx := 0; sx < width; sx++ {
+		pos := float64(sx) * float64(len(rays)) / float64(width)
+		i := int(pos)
+		ray := rays[i]
+		tex, hasTexture := r.Textures[ray.Wt]
+		cosRel := math.Cos(raycasting.NormalizeAngle(r.Player.Angle - ray.Ang))
+
+		// rays without a hit are drawn as only floor and ceiling
+		top, bot := renderMiddle, renderMiddle
+		xTextureIdx := 0
+		face := lighting.FaceOf(ray)
+		columnColor := color.RGBA{0, 0, 0, 255}
+		var wallLight lighting.RGB
+		if hasTexture && ray.Dist > 0 {
+			columnHeight := r.BlockSize * screenHeight / (ray.Dist * cosRel)
+			top = renderMiddle - columnHeight/2
+			bot = renderMiddle + columnHeight/2
+			xTextureIdx = tex.Column(ray, r.BlockSize)
+			if r.Lights != nil {
+				wallLight = r.Lights.Wall(ray)
+			}
+		}
+
+		for sy := 0; sy < height; sy++ {
+			y := float64(sy) + 0.5
+			var clr color.RGBA
+			if y >= top && y < bot {
+				row := min(int((y-top)/(bot-top)*float64(len(tex))), len(tex)-1)
+				columnColor.B = tex[row][xTextureIdx]
+				clr = light.ShadeLit(columnColor, face, ray.Dist, wallLight)
+			} else {
+				clr = r.flatAt(ray, y, renderMiddle, screenHeight, cosRel, columnAngle*(pos-float64(i)), light)
+			}
+			r.Image.SetRGBA(bounds.Min.X+sx, bounds.Min.Y+sy, clr)
+		}
+	}
+}
+
+// flatAt is the floor, ceiling or sky color at screen row y of a column
+func (r *Renderer3D) flatAt(ray raycasting.Ray, y, renderMiddle, screenHeight, cosRel, angleOffset float64, light lighting.Config) color.RGBA {
+	ceiling := y < renderMiddle
+	base := r.BottomColor
+	if ceiling {
+		base = r.TopColor
+	}
+	// the inverse of the column height calculation
+	perpDist := r.BlockSize * screenHeight / (2 * math.Abs(y-renderMiddle))
+	dist := perpDist / cosRel
+	c := raycasting.Coordinate{
+		X: r.Player.Coord.X + math.Cos(ray.Ang)*dist,
+		Y: r.Player.Coord.Y + math.Sin(ray.Ang)*dist,
+	}
+	if ceiling && r.Sky != nil && r.isOutdoor(c) {
+		return r.skyAt(raycasting.NormalizeAngle(ray.Ang+angleOffset), y, renderMiddle, screenHeight)
+	}
+	if r.Lights == nil {
+		return light.ShadeFlat(base, dist)
+	}
+	return light.ShadeFlatLit(base, dist, r.Lights.Floor(c))
+}
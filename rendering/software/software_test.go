@@ -0,0 +1,136 @@
+package software
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/hvassaa/gaster/lighting"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/texture"
+)
+
+var update = flag.Bool("update", false, "overwrite the golden images with the rendered ones")
+
+const blockSize = 40.
+
+func testMap() [][]raycasting.WallType {
+	m := make([][]raycasting.WallType, 10)
+	for y := range m {
+		m[y] = make([]raycasting.WallType, 10)
+		for x := range m[y] {
+			if x == 0 || x == 9 || y == 0 || y == 9 {
+				m[y][x] = 1
+			}
+		}
+	}
+	m[3][5] = 2
+	m[4][5] = 2
+	m[5][5] = 2
+	return m
+}
+
+func testTextures() map[raycasting.WallType]texture.Texture {
+	return map[raycasting.WallType]texture.Texture{
+		1: texture.Load("../../resources/textures/cross.csv"),
+		2: texture.Load("../../resources/textures/asd.csv"),
+	}
+}
+
+// diffPixels counts the pixels where a channel differs by more than a small tolerance,
+// as floating point results can vary slightly between architectures
+func diffPixels(a, b *image.RGBA) int {
+	diff := 0
+	for i := 0; i < len(a.Pix); i += 4 {
+		for c := 0; c < 4; c++ {
+			d := int(a.Pix[i+c]) - int(b.Pix[i+c])
+			if d > 2 || d < -2 {
+				diff++
+				break
+			}
+		}
+	}
+	return diff
+}
+
+func compareGolden(t *testing.T, name string, img *image.RGBA) {
+	path := "testdata/" + name + ".png"
+	if *update {
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("%v, run the tests with -update to create it", err)
+	}
+	defer f.Close()
+	decoded, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := image.NewRGBA(decoded.Bounds())
+	for y := decoded.Bounds().Min.Y; y < decoded.Bounds().Max.Y; y++ {
+		for x := decoded.Bounds().Min.X; x < decoded.Bounds().Max.X; x++ {
+			golden.Set(x, y, decoded.At(x, y))
+		}
+	}
+	if golden.Bounds() != img.Bounds() {
+		t.Fatalf("Golden image is %v, rendered image is %v", golden.Bounds(), img.Bounds())
+	}
+	// allow a few pixels on edges to flip
+	if diff := diffPixels(golden, img); diff > len(img.Pix)/4/1000 {
+		t.Fatalf("%v pixels differ from %v", diff, path)
+	}
+}
+
+func TestGoldenFrames(t *testing.T) {
+	m := testMap()
+	textures := testTextures()
+
+	t.Run("Plain", func(t *testing.T) {
+		p := &player.Player{Coord: &raycasting.Coordinate{X: 100, Y: 200}, Angle: 0.3}
+		rays := raycasting.CastRays(*p.Coord, p.Angle, 60*raycasting.DEG_TO_RAD, 61, blockSize, m)
+		compareGolden(t, "plain", Frame(160, 80, p, rays, blockSize, textures))
+	})
+
+	t.Run("Looking up with sky, fog and a light", func(t *testing.T) {
+		p := &player.Player{Coord: &raycasting.Coordinate{X: 300, Y: 300}, Angle: 3.5, HozAngle: 10}
+		rays := raycasting.CastRays(*p.Coord, p.Angle, 60*raycasting.DEG_TO_RAD, 61, blockSize, m)
+		img := image.NewRGBA(image.Rect(0, 0, 160, 80))
+		r := NewRenderer3D(img, p, blockSize, textures)
+		r.Sky = texture.LoadImage("../../resources/textures/sky.png")
+		r.Outdoor = make([][]bool, len(m))
+		for y := range r.Outdoor {
+			r.Outdoor[y] = make([]bool, len(m[y]))
+			for x := range r.Outdoor[y] {
+				r.Outdoor[y][x] = x < 5
+			}
+		}
+		r.Lighting.FogCurve = lighting.FOG_LINEAR
+		r.Lighting.FogStart = 50
+		r.Lighting.FogEnd = 400
+		r.Lights = lighting.NewLights(blockSize, m)
+		r.Lights.Add(&lighting.PointLight{
+			Coord:     raycasting.Coordinate{X: 260, Y: 260},
+			Color:     color.RGBA{255, 200, 100, 255},
+			Radius:    200,
+			Intensity: 2,
+		})
+		r.Lights.Update(rand.New(rand.NewSource(0)))
+		r.Render(rays)
+		compareGolden(t, "lit", img)
+	})
+}
@@ -0,0 +1,132 @@
+package rendering
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hvassaa/gaster/lighting"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// SPRITE_SEGMENTS is how many corners the ellipse of a sprite without an image has
+const SPRITE_SEGMENTS = 20
+
+// Sprite is something in the world that is not a wall, drawn facing the camera
+type Sprite struct {
+	Coord raycasting.Coordinate
+	// Size is the height as a fraction of a wall. The width follows the image,
+	// or is half the height without one.
+	Size float64
+	// Lift raises the sprite from the floor, as a fraction of a wall
+	Lift float64
+	// Image is drawn if set, otherwise an ellipse in Color.
+	// Color is also used for the dot on the 2D map.
+	Image *ebiten.Image
+	Color color.RGBA
+}
+
+// wallDepth is the distance to the wall hit by ray, along the view direction
+func (r3d *Renderer3D) wallDepth(ray raycasting.Ray) float64 {
+	if ray.Dist == 0 {
+		// nothing was hit
+		return math.Inf(1)
+	}
+	return ray.Dist * math.Cos(raycasting.NormalizeAngle(r3d.Player.Angle-ray.Ang))
+}
+
+// renderSprites draws the sprites farthest first, only in the columns where they are in front of the wall
+func (r3d *Renderer3D) renderSprites(rays []raycasting.Ray, renderMiddle float32, light lighting.Config) {
+	if len(r3d.Sprites) == 0 || len(rays) < 2 {
+		return
+	}
+	type placed struct {
+		Sprite
+		depth, column float64
+	}
+	perRay := r3d.FOV / float64(len(rays)-1)
+	var visible []placed
+	for _, s := range r3d.Sprites {
+		dx, dy := s.Coord.X-r3d.Player.Coord.X, s.Coord.Y-r3d.Player.Coord.Y
+		rel := raycasting.RelativeAngle(math.Atan2(dy, dx), r3d.Player.Angle)
+		depth := math.Hypot(dx, dy) * math.Cos(rel)
+		// behind the camera, or so close it would fill the screen
+		if depth < r3d.BlockSize/10 {
+			continue
+		}
+		visible = append(visible, placed{s, depth, (rel + r3d.FOV/2) / perRay})
+	}
+	sort.Slice(visible, func(i, j int) bool { return visible[i].depth > visible[j].depth })
+
+	xStart := float32(r3d.Screen.Bounds().Min.X)
+	for _, s := range visible {
+		wallHeight := float32(r3d.BlockSize * float64(r3d.ScreenHeight) / s.depth)
+		h := wallHeight * float32(s.Size)
+		w := h / 2
+		if s.Image != nil {
+			w = h * float32(s.Image.Bounds().Dx()) / float32(s.Image.Bounds().Dy())
+		}
+		left := xStart + float32(s.column)*r3d.ColumnWidth - w/2
+		top := renderMiddle + wallHeight/2 - wallHeight*float32(s.Lift) - h
+
+		// each column is drawn centered on its x, so it covers half a column to each side
+		first := max(int(math.Ceil(float64((left-xStart)/r3d.ColumnWidth-0.5))), 0)
+		last := min(int(math.Floor(float64((left+w-xStart)/r3d.ColumnWidth+0.5))), len(rays)-1)
+		// images are tinted by the light, so shading white gives the tint
+		base := s.Color
+		if s.Image != nil {
+			base = color.RGBA{255, 255, 255, 255}
+		}
+		clr := light.ShadeFlat(base, s.depth)
+		if r3d.Lights != nil {
+			clr = light.ShadeFlatLit(base, s.depth, r3d.Lights.Floor(s.Coord))
+		}
+		for i := first; i <= last; i++ {
+			if r3d.wallDepth(rays[i]) <= s.depth {
+				continue
+			}
+			// draw the whole run of columns where the sprite is in front at once
+			j := i
+			for j < last && r3d.wallDepth(rays[j+1]) > s.depth {
+				j++
+			}
+			x0 := xStart + (float32(i)-0.5)*r3d.ColumnWidth
+			x1 := xStart + (float32(j)+0.5)*r3d.ColumnWidth
+			bounds := r3d.Screen.Bounds()
+			clip := image.Rect(int(math.Floor(float64(x0))), bounds.Min.Y, int(math.Ceil(float64(x1))), bounds.Max.Y).Intersect(bounds)
+			r3d.drawSprite(r3d.Screen.SubImage(clip).(*ebiten.Image), s.Sprite, left, top, w, h, clr)
+			i = j
+		}
+	}
+}
+
+// drawSprite draws s in the rectangle at left, top, in clr or tinted by clr if it has an image
+func (r3d *Renderer3D) drawSprite(dst *ebiten.Image, s Sprite, left, top, w, h float32, clr color.RGBA) {
+	if s.Image == nil {
+		xs, ys := make([]float32, SPRITE_SEGMENTS), make([]float32, SPRITE_SEGMENTS)
+		for i := range xs {
+			a := float64(i) / SPRITE_SEGMENTS * raycasting.PI_TWO
+			xs[i] = left + w/2 + float32(math.Cos(a))*w/2
+			ys[i] = top + h/2 + float32(math.Sin(a))*h/2
+		}
+		fillPolygon(dst, xs, ys, clr)
+		return
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(w)/float64(s.Image.Bounds().Dx()), float64(h)/float64(s.Image.Bounds().Dy()))
+	op.GeoM.Translate(float64(left), float64(top))
+	op.ColorScale.ScaleWithColor(clr)
+	op.Filter = ebiten.FilterNearest
+	dst.DrawImage(s.Image, op)
+}
+
+// renderSpriteDots draws the sprites as dots, at the screen position given by transform
+func (r2d *Renderer2D) renderSpriteDots(dst *ebiten.Image, transform func(x, y float64) (float32, float32), radius float32) {
+	for _, s := range r2d.Sprites {
+		x, y := transform(s.Coord.X, s.Coord.Y)
+		vector.DrawFilledCircle(dst, x, y, max(radius*float32(s.Size), 2), s.Color, true)
+	}
+}
@@ -0,0 +1,133 @@
+package terminal
+
+import (
+	"io"
+
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+type Key int
+
+const (
+	KEY_NONE Key = iota
+	KEY_FORWARD
+	KEY_BACKWARD
+	KEY_STRAFE_LEFT
+	KEY_STRAFE_RIGHT
+	KEY_TURN_LEFT
+	KEY_TURN_RIGHT
+	KEY_LOOK_UP
+	KEY_LOOK_DOWN
+	KEY_QUIT
+)
+
+// TURN_STEP is how much a single key press turns, as terminals have no key release events
+const TURN_STEP = 0.08
+
+// ReadKeys reads raw terminal input from in, and sends the keys it recognizes until in fails
+func ReadKeys(in io.Reader, keys chan<- Key) {
+	buf := make([]byte, 64)
+	// escape sequences, like arrow keys, may be split over several reads
+	var pending []byte
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		pending = append(pending, buf[:n]...)
+		for len(pending) > 0 {
+			key, size := parseKey(pending)
+			if size == 0 {
+				break
+			}
+			pending = pending[size:]
+			if key != KEY_NONE {
+				keys <- key
+			}
+		}
+	}
+}
+
+// MAX_SEQUENCE is the longest escape sequence that is waited for, as longer
+// ones are not from a key
+const MAX_SEQUENCE = 16
+
+// parseKey returns the first key in b, and how many bytes it used.
+// It uses no bytes if b is an incomplete escape sequence.
+func parseKey(b []byte) (Key, int) {
+	if b[0] == 0x1b {
+		if len(b) == 1 {
+			return KEY_NONE, 0
+		}
+		if b[1] != '[' {
+			// not a sequence we know, drop the escape
+			return KEY_NONE, 1
+		}
+		// the parameters, like 1;5 of ctrl and up, run until the final byte
+		for i := 2; i < len(b) && i < MAX_SEQUENCE; i++ {
+			if b[i] < 0x40 || b[i] > 0x7e {
+				continue
+			}
+			switch b[i] {
+			case 'A':
+				return KEY_FORWARD, i + 1
+			case 'B':
+				return KEY_BACKWARD, i + 1
+			case 'C':
+				return KEY_TURN_RIGHT, i + 1
+			case 'D':
+				return KEY_TURN_LEFT, i + 1
+			}
+			return KEY_NONE, i + 1
+		}
+		if len(b) >= MAX_SEQUENCE {
+			return KEY_NONE, 1
+		}
+		return KEY_NONE, 0
+	}
+	switch b[0] {
+	case 'w':
+		return KEY_FORWARD, 1
+	case 's':
+		return KEY_BACKWARD, 1
+	case 'a':
+		return KEY_STRAFE_LEFT, 1
+	case 'd':
+		return KEY_STRAFE_RIGHT, 1
+	case 'q':
+		return KEY_TURN_LEFT, 1
+	case 'e':
+		return KEY_TURN_RIGHT, 1
+	case 'r':
+		return KEY_LOOK_UP, 1
+	case 'f':
+		return KEY_LOOK_DOWN, 1
+	case 'x', 3: // 3 is ctrl-c
+		return KEY_QUIT, 1
+	}
+	return KEY_NONE, 1
+}
+
+// Drive applies a key to the player, the same way the windowed game moves it
+func Drive(p *player.Player, key Key, blockSize float64, m [][]raycasting.WallType) {
+	switch key {
+	case KEY_FORWARD:
+		p.Walk(1, 0, blockSize, m)
+	case KEY_BACKWARD:
+		p.Walk(1, raycasting.PI, blockSize, m)
+	case KEY_STRAFE_LEFT:
+		p.Walk(1, -raycasting.PI_HALF, blockSize, m)
+	case KEY_STRAFE_RIGHT:
+		p.Walk(1, raycasting.PI_HALF, blockSize, m)
+	case KEY_TURN_LEFT:
+		p.IncreaseAngle(-TURN_STEP)
+	case KEY_TURN_RIGHT:
+		p.IncreaseAngle(TURN_STEP)
+	case KEY_LOOK_UP:
+		p.IncreaseHozAngle(10)
+	case KEY_LOOK_DOWN:
+		p.IncreaseHozAngle(-10)
+	}
+}
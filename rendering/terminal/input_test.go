@@ -0,0 +1,80 @@
+package terminal
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+// chunks is a reader giving one chunk every read
+type chunks []string
+
+func (c *chunks) Read(b []byte) (int, error) {
+	if len(*c) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, (*c)[0])
+	*c = (*c)[1:]
+	return n, nil
+}
+
+func read(reads ...string) []Key {
+	in := chunks(reads)
+	keys := make(chan Key, 64)
+	go ReadKeys(&in, keys)
+	var res []Key
+	for k := range keys {
+		res = append(res, k)
+	}
+	return res
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		key  Key
+		size int
+	}{
+		{"Letter", "w", KEY_FORWARD, 1},
+		{"Ctrl-c", "\x03", KEY_QUIT, 1},
+		{"Unknown letter", "z", KEY_NONE, 1},
+		{"Arrow", "\x1b[D", KEY_TURN_LEFT, 3},
+		{"Arrow with modifiers", "\x1b[1;5Aw", KEY_FORWARD, 6},
+		{"Delete", "\x1b[3~w", KEY_NONE, 4},
+		{"Lone escape", "\x1b", KEY_NONE, 0},
+		{"Incomplete sequence", "\x1b[1;5", KEY_NONE, 0},
+		{"Not a sequence", "\x1bOw", KEY_NONE, 1},
+		{"Sequence without an end", "\x1b[11111111111111111", KEY_NONE, 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			key, size := parseKey([]byte(tt.in))
+			if key != tt.key || size != tt.size {
+				t.Fatalf("%q should be key %v of %d bytes, got: %v of %d bytes", tt.in, tt.key, tt.size, key, size)
+			}
+		})
+	}
+}
+
+func TestReadKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		reads []string
+		keys  []Key
+	}{
+		{"Keys", []string{"wsad"}, []Key{KEY_FORWARD, KEY_BACKWARD, KEY_STRAFE_LEFT, KEY_STRAFE_RIGHT}},
+		{"Sequence split over reads", []string{"\x1b[", "C", "w"}, []Key{KEY_TURN_RIGHT, KEY_FORWARD}},
+		{"Long sequence split over reads", []string{"\x1b[1;", "5Bq"}, []Key{KEY_BACKWARD, KEY_TURN_LEFT}},
+		{"Long sequences leave nothing behind", []string{"\x1b[3~\x1b[1;2D"}, []Key{KEY_TURN_LEFT}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if keys := read(tt.reads...); !reflect.DeepEqual(keys, tt.keys) {
+				t.Fatalf("Reads %q should give keys %v, got: %v", tt.reads, tt.keys, keys)
+			}
+		})
+	}
+}
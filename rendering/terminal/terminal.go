@@ -0,0 +1,126 @@
+// Package terminal renders the first person view as text with ANSI escape codes,
+// so the engine can run in a terminal without a window.
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// WALL_SHADES are used for walls, from near to far
+const WALL_SHADES = "@%#*+=-:"
+
+// FLOOR_SHADES are used for the floor, from near to far
+const FLOOR_SHADES = "~-.  "
+
+// Renderer implements rendering.Renderer, writing a full frame to Out on each Render
+type Renderer struct {
+	Out           io.Writer
+	Width, Height int
+	Player        *player.Player
+	BlockSize     float64
+	// MaxDist is the distance at which walls get the faintest shade
+	MaxDist float64
+	// Color enables ANSI 256 colors, otherwise only characters are used
+	Color bool
+	// WallColors are ANSI 256 color codes for each wall type
+	WallColors map[raycasting.WallType]int
+	FloorColor int
+	buf        bytes.Buffer
+}
+
+func New(out io.Writer, width, height int, player *player.Player, blockSize float64) *Renderer {
+	return &Renderer{
+		Out:       out,
+		Width:     width,
+		Height:    height,
+		Player:    player,
+		BlockSize: blockSize,
+		MaxDist:   blockSize * 15,
+		Color:     true,
+		WallColors: map[raycasting.WallType]int{
+			1: 33,
+			2: 160,
+		},
+		FloorColor: 244,
+	}
+}
+
+func shade(shades string, f float64) byte {
+	idx := int(f * float64(len(shades)))
+	return shades[min(max(idx, 0), len(shades)-1)]
+}
+
+func (r *Renderer) Render(rays []raycasting.Ray) {
+	if len(rays) == 0 || r.Width <= 0 || r.Height <= 0 {
+		return
+	}
+	screenHeight := float64(r.Height)
+	renderMiddle := screenHeight/2 + r.Player.HozAngle*screenHeight*3/180
+	chars := make([][]byte, r.Height)
+	colors := make([][]int, r.Height)
+	for y := range chars {
+		chars[y] = bytes.Repeat([]byte{' '}, r.Width)
+		colors[y] = make([]int, r.Width)
+	}
+
+	for x := 0; x < r.Width; x++ {
+		ray := rays[x*len(rays)/r.Width]
+		top, bot := renderMiddle, renderMiddle
+		var wallChar byte
+		if ray.Dist > 0 {
+			// this avoid fisheye
+			noFish := math.Cos(raycasting.NormalizeAngle(r.Player.Angle-ray.Ang)) * ray.Dist
+			columnHeight := r.BlockSize * screenHeight / noFish
+			top = renderMiddle - columnHeight/2
+			bot = renderMiddle + columnHeight/2
+			f := ray.Dist / r.MaxDist
+			if ray.Dir == raycasting.VERTICAL {
+				// vertical hits are a shade darker, so corners stand out
+				f += 1. / float64(len(WALL_SHADES))
+			}
+			wallChar = shade(WALL_SHADES, f)
+		}
+
+		for y := 0; y < r.Height; y++ {
+			fy := float64(y) + 0.5
+			switch {
+			case fy >= top && fy < bot:
+				chars[y][x] = wallChar
+				colors[y][x] = r.WallColors[ray.Wt]
+			case fy >= bot:
+				// the floor gets fainter towards the horizon
+				f := 1 - (fy-renderMiddle)/(screenHeight-renderMiddle)
+				chars[y][x] = shade(FLOOR_SHADES, f)
+				colors[y][x] = r.FloorColor
+			}
+		}
+	}
+
+	r.buf.Reset()
+	// move the cursor to the top left, and draw over the previous frame
+	r.buf.WriteString("\x1b[H")
+	for y := range chars {
+		current := -1
+		for x, c := range chars[y] {
+			if r.Color && colors[y][x] != current {
+				current = colors[y][x]
+				fmt.Fprintf(&r.buf, "\x1b[38;5;%dm", current)
+			}
+			r.buf.WriteByte(c)
+		}
+		if r.Color {
+			r.buf.WriteString("\x1b[0m")
+		}
+		if y < len(chars)-1 {
+			// raw terminals do not return the carriage on newline
+			r.buf.WriteString("\r\n")
+		}
+	}
+	r.Out.Write(r.buf.Bytes())
+}
@@ -0,0 +1,62 @@
+package rendering
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hvassaa/gaster/lighting"
+)
+
+const (
+	// RECOIL_TICKS is how long the gun takes to come back after a shot, and FLASH_TICKS how long the muzzle flash shows
+	RECOIL_TICKS = 8
+	FLASH_TICKS  = 3
+)
+
+// Gun is the weapon of the player, drawn at the bottom of the view
+type Gun struct {
+	Color color.RGBA
+	// Barrels is how many barrels are side by side
+	Barrels int
+	// SinceShot is the ticks since the gun fired, for the recoil and muzzle flash
+	SinceShot int
+	// Lowered is how far the gun is out of view, from 0 to 1, as while reloading
+	Lowered float64
+}
+
+// renderGun draws the gun in the light where the player stands
+func (r3d *Renderer3D) renderGun(light lighting.Config) {
+	gun := r3d.Gun
+	bounds := r3d.Screen.Bounds()
+	h := r3d.ScreenHeight * 0.4
+	barrel := h / 8
+	w := barrel * float32(max(gun.Barrels, 1))
+	cx := float32(bounds.Min.X) + r3d.ScreenWidth/2
+	// the gun kicks down when fired, and comes back up
+	top := float32(bounds.Max.Y) - h + h*float32(gun.Lowered)
+	if gun.SinceShot < RECOIL_TICKS {
+		top += h / 10 * float32(RECOIL_TICKS-gun.SinceShot) / RECOIL_TICKS
+	}
+
+	clr := light.ShadeFlat(gun.Color, 0)
+	if r3d.Lights != nil {
+		clr = light.ShadeFlatLit(gun.Color, 0, r3d.Lights.Floor(*r3d.Player.Coord))
+	}
+	dark := color.RGBA{clr.R / 2, clr.G / 2, clr.B / 2, clr.A}
+
+	if gun.SinceShot < FLASH_TICKS {
+		vector.DrawFilledCircle(r3d.Screen, cx, top, w, color.RGBA{255, 200, 80, 200}, true)
+		vector.DrawFilledCircle(r3d.Screen, cx, top, w/2, color.RGBA{255, 255, 220, 255}, true)
+	}
+	// the barrels, and the body widening towards the bottom of the screen
+	for i := 0; i < max(gun.Barrels, 1); i++ {
+		x := cx - w/2 + float32(i)*barrel
+		vector.DrawFilledRect(r3d.Screen, x, top, barrel, h/2, clr, false)
+		vector.StrokeRect(r3d.Screen, x, top, barrel, h/2, 1, dark, false)
+	}
+	body := top + h/2
+	fillPolygon(r3d.Screen,
+		[]float32{cx - w/2 - barrel/2, cx + w/2 + barrel/2, cx + w/2 + barrel*1.5, cx - w/2 - barrel*1.5},
+		[]float32{body, body, float32(bounds.Max.Y), float32(bounds.Max.Y)},
+		dark)
+}
@@ -0,0 +1,102 @@
+// Package replay records the input actions of every tick, so a game can be played back exactly
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hvassaa/gaster/input"
+	"github.com/hvassaa/gaster/weapon"
+)
+
+// VERSION is written to every replay, and replays of other versions are not played
+const VERSION = 1
+
+// DEMO_MAP is the map ID of the built in demo map, other IDs are paths to CSV maps
+const DEMO_MAP = "demo"
+
+// Start is the state of the player when the recording started
+type Start struct {
+	X, Y, Angle, HozAngle, Speed float64
+	Noclip                       bool
+	// Health is 0 in replays from before it was recorded, which start at full health
+	Health int `json:",omitempty"`
+	// Weapons is the ammo of each weapon, and Weapon the one in hand
+	Weapons []weapon.State `json:",omitempty"`
+	Weapon  int            `json:",omitempty"`
+}
+
+type Replay struct {
+	Version int
+	// Seed is used for the random number generator when playback starts
+	Seed   int64
+	Map    string
+	Start  Start
+	Frames []input.Frame
+}
+
+func New(seed int64, mapID string, start Start) *Replay {
+	return &Replay{Version: VERSION, Seed: seed, Map: mapID, Start: start}
+}
+
+func (r *Replay) Add(f input.Frame) {
+	r.Frames = append(r.Frames, f)
+}
+
+// Save writes the replay as JSON. Numbers are written so they are read back bit for bit.
+func (r *Replay) Save(path string) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func Load(path string) (*Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replay{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	if r.Version != VERSION {
+		return nil, fmt.Errorf("%v: replay version %d, expected %d", path, r.Version, VERSION)
+	}
+	if r.Map == "" {
+		return nil, errors.New(path + ": replay has no map")
+	}
+	return r, nil
+}
+
+// Playback gives the frames of a replay one at a time
+type Playback struct {
+	Replay *Replay
+	tick   int
+}
+
+func NewPlayback(r *Replay) *Playback {
+	return &Playback{Replay: r}
+}
+
+// Poll is the frame for the next tick, or an empty frame when the replay is done
+func (p *Playback) Poll() input.Frame {
+	if p.Done() {
+		return input.Frame{}
+	}
+	f := p.Replay.Frames[p.tick]
+	p.tick++
+	return f
+}
+
+func (p *Playback) Done() bool {
+	return p.tick >= len(p.Replay.Frames)
+}
+
+// Tick is how many frames have been played
+func (p *Playback) Tick() int {
+	return p.tick
+}
@@ -0,0 +1,84 @@
+package replay
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hvassaa/gaster/input"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/sim"
+	"github.com/hvassaa/gaster/weapon"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replay.json")
+	r := New(42, DEMO_MAP, Start{X: 600.1, Y: 1. / 3, Angle: math.Pi / 7, Speed: 10, Health: 37, Weapons: []weapon.State{{Name: "pistol", Loaded: 3, Ammo: 20}}, Weapon: 1})
+	r.Add(input.Frame{Move: 1, Turn: 0.1 + 0.2, Look: -1e-17})
+	r.Add(input.Frame{Strafe: -0.3333333333333333, Turn: math.Nextafter(0.04, 1), Buttons: input.USE})
+	if err := r.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("Frames are bit identical", func(t *testing.T) {
+		for i, f := range r.Frames {
+			if loaded.Frames[i] != f {
+				t.Fatalf("Frame %d should be %+v, got: %+v", i, f, loaded.Frames[i])
+			}
+		}
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		if loaded.Seed != 42 || loaded.Map != DEMO_MAP || !reflect.DeepEqual(loaded.Start, r.Start) {
+			t.Fatalf("Header should be kept, got: %+v", loaded)
+		}
+	})
+}
+
+func TestPlaybackTrack(t *testing.T) {
+	m := make([][]raycasting.WallType, 10)
+	for y := range m {
+		m[y] = make([]raycasting.WallType, 10)
+		m[y][0], m[y][9] = 1, 1
+	}
+	for x := range m[0] {
+		m[0][x], m[9][x] = 1, 1
+	}
+
+	r := New(1, DEMO_MAP, Start{X: 200, Y: 200, Speed: 7})
+	for i := 0; i < 200; i++ {
+		r.Add(input.Frame{Move: 1, Turn: 0.013 * float64(i%7)})
+	}
+	// the world steps with the frames the way the game does
+	track := func(r *Replay) []raycasting.Coordinate {
+		p := &player.Player{Coord: &raycasting.Coordinate{X: r.Start.X, Y: r.Start.Y}, Angle: r.Start.Angle, Speed: r.Start.Speed}
+		w := sim.New(p, m, 20, r.Seed)
+		var res []raycasting.Coordinate
+		for pb := NewPlayback(r); !pb.Done(); {
+			w.Update(pb)
+			res = append(res, *p.Coord)
+		}
+		return res
+	}
+
+	path := filepath.Join(t.TempDir(), "replay.json")
+	if err := r.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, got := track(r), track(loaded)
+	for i := range want {
+		if want[i] != got[i] {
+			t.Fatalf("Tick %d should be at %v, got: %v", i, want[i], got[i])
+		}
+	}
+}
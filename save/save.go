@@ -0,0 +1,188 @@
+// Package save writes the game state to versioned files in numbered or named slots
+package save
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/settings"
+	"github.com/hvassaa/gaster/weapon"
+)
+
+// VERSION is the version of saves written now. Older saves are migrated
+// forward when loaded, newer ones are refused.
+const VERSION = 1
+
+// QUICK_SLOT is the slot used by quick save and quick load
+const QUICK_SLOT = "quick"
+
+// Migration changes a decoded save of one version into the next version
+type Migration func(raw map[string]any) error
+
+// MIGRATIONS[i] migrates a save from version i+1 to i+2
+var MIGRATIONS = []Migration{}
+
+type Player struct {
+	X, Y, Angle, HozAngle, Speed float64
+	Health, MaxHealth            int
+	// Weapons is the ammo of each weapon, and Weapon the one in hand
+	Weapons []weapon.State `json:",omitempty"`
+	Weapon  int            `json:",omitempty"`
+}
+
+// Entity is anything besides the player that lives in the world
+type Entity struct {
+	Kind        string
+	X, Y, Angle float64
+	Health      int
+	// State is entity specific, like what an enemy is doing
+	State string `json:",omitempty"`
+}
+
+type Save struct {
+	Version int
+	Time    time.Time
+	// MapID is where the map was loaded from, Map is the map as it is now
+	MapID    string
+	Map      [][]raycasting.WallType
+	Player   Player
+	Noclip   bool
+	Entities []Entity
+	Explored [][]bool
+	Settings settings.Settings
+}
+
+func path(dir, slot string) string {
+	return filepath.Join(dir, slot+".json")
+}
+
+// Write saves s in a slot, replacing what was there
+func Write(dir, slot string, s *Save) error {
+	if err := validSlot(slot); err != nil {
+		return err
+	}
+	s.Version = VERSION
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	// write next to the old save first, so a crash does not leave half a save
+	tmp := path(dir, slot) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path(dir, slot))
+}
+
+// Read loads the save in a slot, migrating it to the current version
+func Read(dir, slot string) (*Save, error) {
+	if err := validSlot(slot); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path(dir, slot))
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data, MIGRATIONS)
+}
+
+// Decode parses a save of any version up to VERSION, using migrations to bring it up to date
+func Decode(data []byte, migrations []Migration) (*Save, error) {
+	raw := map[string]any{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	v, ok := raw["Version"].(float64)
+	if !ok || v < 1 {
+		return nil, errors.New("save has no version")
+	}
+	version := int(v)
+	target := len(migrations) + 1
+	if version > target {
+		return nil, fmt.Errorf("save version %d is newer than %d", version, target)
+	}
+	for ; version < target; version++ {
+		if err := migrations[version-1](raw); err != nil {
+			return nil, fmt.Errorf("migrating save from version %d: %w", version, err)
+		}
+	}
+	raw["Version"] = version
+
+	// encode the migrated save again, so it can be decoded into the struct
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	s := &Save{Settings: settings.Default()}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if len(s.Map) == 0 {
+		return nil, errors.New("save has no map")
+	}
+	return s, nil
+}
+
+// Slot is a save found in a directory
+type Slot struct {
+	Name  string
+	Time  time.Time
+	MapID string
+}
+
+// List gives the slots in dir, newest first. A missing dir has no slots.
+func List(dir string) ([]Slot, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var res []Slot
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		// only the header is needed, but saves are small enough to read whole
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var header struct {
+			Time  time.Time
+			MapID string
+		}
+		if json.Unmarshal(data, &header) != nil {
+			continue
+		}
+		res = append(res, Slot{Name: name, Time: header.Time, MapID: header.MapID})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Time.After(res[j].Time) })
+	return res, nil
+}
+
+func Delete(dir, slot string) error {
+	if err := validSlot(slot); err != nil {
+		return err
+	}
+	return os.Remove(path(dir, slot))
+}
+
+// validSlot keeps slot names from reaching outside the save directory
+func validSlot(slot string) error {
+	if slot == "" || strings.ContainsAny(slot, `/\.`) {
+		return errors.New("invalid slot name: " + slot)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package save
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/weapon"
+)
+
+func testSave() *Save {
+	return &Save{
+		Time:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		MapID:    "demo",
+		Map:      [][]raycasting.WallType{{1, 1}, {1, 0}},
+		Player:   Player{X: 12.5, Y: 1. / 3, Angle: 2, Speed: 10, Health: 50, MaxHealth: 100, Weapons: []weapon.State{{Name: "pistol", Loaded: 3, Ammo: 20}}},
+		Explored: [][]bool{{true, false}, {false, true}},
+		Entities: []Entity{{Kind: "enemy", X: 3, Y: 4, Health: 20}},
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	dir := t.TempDir()
+	if err := Write(dir, "1", testSave()); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Read(dir, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testSave()
+	if !reflect.DeepEqual(s.Player, want.Player) || s.MapID != want.MapID || !s.Time.Equal(want.Time) {
+		t.Fatalf("Save should read back the same, got: %+v", s)
+	}
+	if s.Map[1][1] != 0 || s.Map[0][1] != 1 || !s.Explored[1][1] || s.Explored[0][1] {
+		t.Fatalf("Map and explored cells should read back the same, got: %v %v", s.Map, s.Explored)
+	}
+	if len(s.Entities) != 1 || s.Entities[0] != want.Entities[0] {
+		t.Fatalf("Entities should read back the same, got: %v", s.Entities)
+	}
+	if s.Version != VERSION {
+		t.Fatalf("Save should have the current version, got: %v", s.Version)
+	}
+}
+
+func TestDecodeMigrations(t *testing.T) {
+	// version 1 had the speed at the top level, version 2 moved it into the player
+	old := []byte(`{"Version": 1, "MapID": "demo", "Map": [[1]], "Speed": 7, "Player": {"X": 1}}`)
+	migrations := []Migration{
+		func(raw map[string]any) error {
+			raw["Player"].(map[string]any)["Speed"] = raw["Speed"]
+			delete(raw, "Speed")
+			return nil
+		},
+	}
+
+	t.Run("Old saves are migrated", func(t *testing.T) {
+		s, err := Decode(old, migrations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Player.Speed != 7 || s.Player.X != 1 || s.Version != 2 {
+			t.Fatalf("Save should be migrated to version 2, got: %+v", s)
+		}
+	})
+
+	t.Run("Newer saves are refused", func(t *testing.T) {
+		if _, err := Decode([]byte(`{"Version": 3, "Map": [[1]]}`), migrations); err == nil {
+			t.Fatalf("Save from a newer version should fail")
+		}
+	})
+
+	t.Run("Saves without a version are refused", func(t *testing.T) {
+		if _, err := Decode([]byte(`{"Map": [[1]]}`), migrations); err == nil {
+			t.Fatalf("Save without a version should fail")
+		}
+	})
+}
+
+func TestSlots(t *testing.T) {
+	dir := t.TempDir()
+	older, newer := testSave(), testSave()
+	newer.Time = older.Time.Add(time.Hour)
+	if err := Write(dir, "1", older); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(dir, QUICK_SLOT, newer); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("List newest first", func(t *testing.T) {
+		slots, err := List(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(slots) != 2 || slots[0].Name != QUICK_SLOT || slots[1].Name != "1" {
+			t.Fatalf("Slots should be listed newest first, got: %v", slots)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := Delete(dir, "1"); err != nil {
+			t.Fatal(err)
+		}
+		if slots, _ := List(dir); len(slots) != 1 {
+			t.Fatalf("Deleted slot should not be listed, got: %v", slots)
+		}
+	})
+
+	t.Run("Slot names stay in the directory", func(t *testing.T) {
+		if err := Write(dir, "../x", testSave()); err == nil {
+			t.Fatalf("Slot name with a path should fail")
+		}
+	})
+
+	t.Run("Missing directory has no slots", func(t *testing.T) {
+		if slots, err := List(dir + "/missing"); err != nil || slots != nil {
+			t.Fatalf("Missing directory should have no slots, got: %v %v", slots, err)
+		}
+	})
+}
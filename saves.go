@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hvassaa/gaster/console"
+	"github.com/hvassaa/gaster/save"
+	"github.com/hvassaa/gaster/scene"
+)
+
+// SAVE_SLOTS is how many numbered slots the menus show
+const SAVE_SLOTS = 5
+
+// snapshot is the current state as a save
+func (g *Game) snapshot() *save.Save {
+	p := g.world.Player
+	return &save.Save{
+		Time:  time.Now(),
+		MapID: g.mapID,
+		Map:   g.world.Map,
+		Player: save.Player{
+			X: p.Coord.X, Y: p.Coord.Y, Angle: p.Angle, HozAngle: p.HozAngle, Speed: p.Speed,
+			Health: p.Health, MaxHealth: p.MaxHealth,
+			Weapons: g.world.Arsenal.States(), Weapon: g.world.Arsenal.Current,
+		},
+		Noclip:   g.world.Noclip,
+		Entities: g.enemyEntities(),
+		Explored: g.explored.Cells,
+		Settings: g.settings,
+	}
+}
+
+// restoreSave puts the game in the state of s. Display, effect and control
+// settings are kept, as they belong to the machine more than to the save.
+func (g *Game) restoreSave(s *save.Save) {
+	if g.recording != nil {
+		g.recording = nil
+		log.Printf("recording stopped, as loading a save cannot be replayed")
+	}
+	g.setMap(s.MapID, s.Map)
+	g.restoreEnemies(s.Entities)
+	p := g.world.Player
+	p.Coord.X, p.Coord.Y, p.Angle, p.HozAngle, p.Speed = s.Player.X, s.Player.Y, s.Player.Angle, s.Player.HozAngle, s.Player.Speed
+	p.Health, p.MaxHealth = s.Player.Health, s.Player.MaxHealth
+	g.world.Arsenal.Restore(s.Player.Weapons, s.Player.Weapon)
+	g.world.Noclip = s.Noclip
+	if len(s.Explored) == len(s.Map) {
+		g.explored.Cells = s.Explored
+	}
+	g.settings.View = s.Settings.View
+	g.updateRenders = true
+}
+
+func (g *Game) saveGame(slot string) error {
+	if g.playback != nil {
+		return errors.New("cannot save while a replay is playing")
+	}
+	// a save has one player, so the others would start over where the first one is
+	if len(g.locals) > 0 {
+		return errors.New("cannot save while sharing the screen")
+	}
+	if err := save.Write(g.saveDir, slot, g.snapshot()); err != nil {
+		return err
+	}
+	log.Printf("saved to slot %v", slot)
+	return nil
+}
+
+func (g *Game) loadGame(slot string) error {
+	if g.playback != nil {
+		return errors.New("cannot load while a replay is playing")
+	}
+	if g.client != nil {
+		return errors.New("cannot load while online, the server decides where the player is")
+	}
+	s, err := save.Read(g.saveDir, slot)
+	if err != nil {
+		return err
+	}
+	g.restoreSave(s)
+	log.Printf("loaded slot %v", slot)
+	return nil
+}
+
+// slotLabel describes what is in a slot
+func (g *Game) slotLabel(slot string) string {
+	slots, err := save.List(g.saveDir)
+	if err != nil {
+		return slot + "  ?"
+	}
+	for _, s := range slots {
+		if s.Name == slot {
+			return fmt.Sprintf("%v  %v  %v", slot, s.Time.Format("2006-01-02 15:04"), s.MapID)
+		}
+	}
+	return slot + "  empty"
+}
+
+// newSlotsMenu lists the quick slot and the numbered slots, and saves or loads the one picked
+func (g *Game) newSlotsMenu(saving bool) scene.Scene {
+	title, use := "load game", g.loadGame
+	if saving {
+		title, use = "save game", g.saveGame
+	}
+	slots := []string{save.QUICK_SLOT}
+	for i := 1; i <= SAVE_SLOTS; i++ {
+		slots = append(slots, fmt.Sprint(i))
+	}
+	var items []scene.Item
+	for _, slot := range slots {
+		slot := slot
+		label := g.slotLabel(slot)
+		items = append(items, scene.Item{
+			Label: func() string { return label },
+			Activate: func() {
+				if err := use(slot); err != nil {
+					log.Printf("%v: %v", title, err)
+					return
+				}
+				// back to the game after loading, or to the pause menu after saving
+				g.scenes.Pop()
+				if !saving {
+					g.scenes.Pop()
+				}
+			},
+		})
+	}
+	items = append(items, scene.Button("Back", g.scenes.Pop))
+	m := scene.NewMenu(title, g.hud.Face, items...)
+	m.Back = g.scenes.Pop
+	return &menuScene{g: g, menu: m}
+}
+
+func (g *Game) registerSaveCommands(c *console.Console) {
+	c.Register(console.Command{
+		Name:  "save",
+		Usage: "[slot]",
+		Help:  "saves the game, to the quick slot if none is given",
+		Run: func(args []string) (string, error) {
+			slot := save.QUICK_SLOT
+			if len(args) > 0 {
+				slot = args[0]
+			}
+			return "", g.saveGame(slot)
+		},
+	})
+	c.Register(console.Command{
+		Name:  "load",
+		Usage: "[slot]",
+		Help:  "loads a saved game, from the quick slot if none is given",
+		Run: func(args []string) (string, error) {
+			slot := save.QUICK_SLOT
+			if len(args) > 0 {
+				slot = args[0]
+			}
+			return "", g.loadGame(slot)
+		},
+	})
+	c.Register(console.Command{
+		Name: "saves",
+		Help: "lists the saved games",
+		Run: func(args []string) (string, error) {
+			slots, err := save.List(g.saveDir)
+			if err != nil {
+				return "", err
+			}
+			var lines []string
+			for _, s := range slots {
+				lines = append(lines, fmt.Sprintf("%v  %v  %v", s.Name, s.Time.Format("2006-01-02 15:04:05"), s.MapID))
+			}
+			if len(lines) == 0 {
+				return "no saves", nil
+			}
+			return strings.Join(lines, "\n"), nil
+		},
+	})
+	c.Register(console.Command{
+		Name:  "delete save",
+		Usage: "<slot>",
+		Help:  "deletes a saved game",
+		Run: func(args []string) (string, error) {
+			if len(args) != 1 {
+				return "", errors.New("expected a slot")
+			}
+			return "", save.Delete(g.saveDir, args[0])
+		},
+	})
+}
@@ -0,0 +1,162 @@
+package scene
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hvassaa/gaster/hud"
+	"golang.org/x/image/font"
+)
+
+// TITLE_SCALE is how much larger the title is than the items
+const TITLE_SCALE = 3
+
+type Item struct {
+	// Label is called every frame, so it can show a changing value
+	Label func() string
+	// Activate is called on enter or a click, nil items cannot be selected
+	Activate func()
+	// Change is called with -1 or 1 on the left and right keys, or nil if the item has no value
+	Change func(delta int)
+}
+
+// Label is an item that only shows text
+func Label(text string) Item {
+	return Item{Label: func() string { return text }}
+}
+
+// Button is an item with a fixed label
+func Button(text string, activate func()) Item {
+	return Item{Label: func() string { return text }, Activate: activate}
+}
+
+// Menu is a centered list of items, used with the keyboard or the mouse
+type Menu struct {
+	Title    string
+	Items    []Item
+	Face     font.Face
+	Selected int
+	// Back is called on escape, or nil to ignore it
+	Back                     func()
+	Background               color.Color
+	TextColor, SelectedColor color.Color
+	rects                    []image.Rectangle
+	titleImage               *ebiten.Image
+	titleText                string
+}
+
+func NewMenu(title string, face font.Face, items ...Item) *Menu {
+	m := &Menu{
+		Title:         title,
+		Items:         items,
+		Face:          face,
+		Background:    color.RGBA{0, 0, 0, 170},
+		TextColor:     color.RGBA{200, 200, 200, 255},
+		SelectedColor: color.RGBA{255, 220, 80, 255},
+	}
+	m.Selected = m.next(-1, 1)
+	return m
+}
+
+func (m *Menu) selectable(i int) bool {
+	return m.Items[i].Activate != nil || m.Items[i].Change != nil
+}
+
+// next is the first selectable item after i in direction dir, or i if there is none
+func (m *Menu) next(i, dir int) int {
+	for j := 1; j <= len(m.Items); j++ {
+		k := ((i+dir*j)%len(m.Items) + len(m.Items)) % len(m.Items)
+		if m.selectable(k) {
+			return k
+		}
+	}
+	return i
+}
+
+// Update handles the keys, and the mouse at cursor, which is in the
+// coordinates of the image the menu is drawn on
+func (m *Menu) Update(cursor image.Point) {
+	if len(m.Items) == 0 {
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) && m.Back != nil {
+		m.Back()
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+		m.Selected = m.next(m.Selected, 1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+		m.Selected = m.next(m.Selected, -1)
+	}
+
+	clicked := false
+	for i, r := range m.rects {
+		if cursor.In(r) && m.selectable(i) {
+			m.Selected = i
+			clicked = inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)
+		}
+	}
+
+	item := m.Items[m.Selected]
+	if item.Change != nil {
+		if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
+			item.Change(-1)
+		} else if inpututil.IsKeyJustPressed(ebiten.KeyRight) || (clicked && item.Activate == nil) {
+			item.Change(1)
+		}
+	}
+	if item.Activate != nil && (inpututil.IsKeyJustPressed(ebiten.KeyEnter) || clicked) {
+		item.Activate()
+	}
+}
+
+func (m *Menu) Draw(screen *ebiten.Image) {
+	bounds := screen.Bounds()
+	vector.DrawFilledRect(screen, float32(bounds.Min.X), float32(bounds.Min.Y), float32(bounds.Dx()), float32(bounds.Dy()), m.Background, false)
+
+	lineHeight := m.Face.Metrics().Height.Ceil()
+	itemsHeight := len(m.Items) * lineHeight * 3 / 2
+	titleHeight := lineHeight * TITLE_SCALE * 2
+	y := bounds.Min.Y + (bounds.Dy()-itemsHeight-titleHeight)/2
+
+	m.drawTitle(screen, y)
+	y += titleHeight
+
+	m.rects = m.rects[:0]
+	for i, item := range m.Items {
+		label := item.Label()
+		if item.Change != nil {
+			label = "< " + label + " >"
+		}
+		size := hud.TextSize(m.Face, label)
+		x := bounds.Min.X + (bounds.Dx()-size.X)/2
+		clr := m.TextColor
+		if i == m.Selected && m.selectable(i) {
+			clr = m.SelectedColor
+		}
+		hud.DrawText(screen, m.Face, label, x, y, clr)
+		m.rects = append(m.rects, image.Rect(x, y, x+size.X, y+size.Y))
+		y += lineHeight * 3 / 2
+	}
+}
+
+// drawTitle draws the title scaled up, since the fonts are small
+func (m *Menu) drawTitle(screen *ebiten.Image, y int) {
+	if m.Title == "" {
+		return
+	}
+	size := hud.TextSize(m.Face, m.Title)
+	if m.titleImage == nil || m.titleText != m.Title {
+		m.titleImage = ebiten.NewImage(size.X+1, size.Y+1)
+		hud.DrawText(m.titleImage, m.Face, m.Title, 0, 0, m.SelectedColor)
+		m.titleText = m.Title
+	}
+	bounds := screen.Bounds()
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(TITLE_SCALE, TITLE_SCALE)
+	op.GeoM.Translate(float64(bounds.Min.X+(bounds.Dx()-size.X*TITLE_SCALE)/2), float64(y))
+	screen.DrawImage(m.titleImage, op)
+}
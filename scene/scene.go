@@ -0,0 +1,74 @@
+// Package scene runs a stack of scenes, like the game with a pause menu on top
+package scene
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+type Scene interface {
+	// Update is only called for the scene on top of the stack
+	Update(m *Manager) error
+	// Draw is called for every scene in the stack, bottom first, so menus can be drawn over the game
+	Draw(screen *ebiten.Image)
+}
+
+// Entered is implemented by scenes that need to know when they are on top of the stack again
+type Entered interface {
+	Enter()
+}
+
+type Manager struct {
+	stack []Scene
+}
+
+func NewManager(first Scene) *Manager {
+	m := &Manager{}
+	m.Push(first)
+	return m
+}
+
+func (m *Manager) Current() Scene {
+	if len(m.stack) == 0 {
+		return nil
+	}
+	return m.stack[len(m.stack)-1]
+}
+
+func (m *Manager) Push(s Scene) {
+	m.stack = append(m.stack, s)
+	enter(s)
+}
+
+// Pop removes the scene on top, unless it is the only one
+func (m *Manager) Pop() {
+	if len(m.stack) < 2 {
+		return
+	}
+	m.stack = m.stack[:len(m.stack)-1]
+	enter(m.Current())
+}
+
+// Replace removes every scene, and starts s
+func (m *Manager) Replace(s Scene) {
+	m.stack = m.stack[:0]
+	m.Push(s)
+}
+
+func enter(s Scene) {
+	if e, ok := s.(Entered); ok {
+		e.Enter()
+	}
+}
+
+func (m *Manager) Update() error {
+	if s := m.Current(); s != nil {
+		return s.Update(m)
+	}
+	return nil
+}
+
+func (m *Manager) Draw(screen *ebiten.Image) {
+	for _, s := range m.stack {
+		s.Draw(screen)
+	}
+}
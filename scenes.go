@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/hvassaa/gaster/automap"
+	"github.com/hvassaa/gaster/display"
+	"github.com/hvassaa/gaster/hud"
+	"github.com/hvassaa/gaster/input"
+	"github.com/hvassaa/gaster/replay"
+	"github.com/hvassaa/gaster/scene"
+)
+
+// KEYBINDS are the keys that cannot be rebound, shown below the actions in the settings
+var KEYBINDS = []string{
+	"mouse  look",
+	"1-9  layouts",
+	"F1  HUD",
+	"F3  ray inspector, F4  ray colors",
+	"F5  quick save, F8  quick load",
+	"F9  record input, F10  record video, F12  screenshot",
+	"F11  fullscreen",
+	"`  console",
+	"Backspace  quit",
+}
+
+// playScene is the game being played
+type playScene struct {
+	g *Game
+}
+
+func (p *playScene) Update(m *scene.Manager) error {
+	return p.g.updatePlay(m)
+}
+
+func (p *playScene) Draw(screen *ebiten.Image) {
+	p.g.drawFrame(screen)
+}
+
+// Enter captures the mouse again, and forgets where it was so the view does not jump
+func (p *playScene) Enter() {
+	if !p.g.inspect {
+		ebiten.SetCursorMode(ebiten.CursorModeCaptured)
+	}
+	p.g.input.ResetCursor()
+}
+
+// menuScene shows a menu, with the mouse free to use it
+type menuScene struct {
+	g    *Game
+	menu *scene.Menu
+	// background is drawn below the menu, or nil to draw what is below in the stack
+	background color.Color
+}
+
+func (ms *menuScene) Update(m *scene.Manager) error {
+	ms.menu.Update(ms.g.frameCursor())
+	return ms.g.quit
+}
+
+func (ms *menuScene) Draw(screen *ebiten.Image) {
+	if ms.background != nil {
+		screen.Fill(ms.background)
+	}
+	ms.menu.Draw(screen)
+}
+
+func (ms *menuScene) Enter() {
+	ebiten.SetCursorMode(ebiten.CursorModeVisible)
+}
+
+// frameCursor is the cursor position in the frame the scenes are drawn on
+func (g *Game) frameCursor() image.Point {
+	x, y := ebiten.CursorPosition()
+	x, y = display.ToFrame(x, y, g.frameScale, g.frameX, g.frameY)
+	return image.Pt(x, y)
+}
+
+func (g *Game) newTitleMenu() scene.Scene {
+	items := []scene.Item{
+		scene.Button("Start", func() { g.scenes.Replace(&playScene{g}) }),
+	}
+	if _, err := os.Stat(g.demoFile); err == nil {
+		items = append(items, scene.Button("Watch demo", g.playDemo))
+	}
+	items = append(items,
+		scene.Button("Settings", func() { g.scenes.Push(g.newSettingsMenu()) }),
+		scene.Button("Quit", g.exit),
+	)
+	return &menuScene{
+		g:          g,
+		menu:       scene.NewMenu("gaster", g.hud.Face, items...),
+		background: color.RGBA{15, 20, 25, 255},
+	}
+}
+
+// playDemo plays the demo replay, and then goes back to the title menu
+// with the game as it was before
+func (g *Game) playDemo() {
+	r, err := replay.Load(g.demoFile)
+	if err != nil {
+		log.Printf("could not play demo: %v", err)
+		return
+	}
+	// the game is put back like a save, with the projectiles that were flying
+	before, projectiles := g.snapshot(), g.world.Projectiles
+	err = g.startPlayback(r, func() {
+		g.restoreSave(before)
+		g.world.Projectiles = projectiles
+		g.scenes.Replace(g.newTitleMenu())
+	})
+	if err != nil {
+		log.Printf("could not play demo: %v", err)
+		return
+	}
+	// the demo explores on its own, as the snapshot shares the explored cells
+	g.explored = automap.New(g.world.Map)
+	g.updateRenders = true
+	g.scenes.Replace(&playScene{g})
+}
+
+func (g *Game) newPauseMenu() scene.Scene {
+	m := scene.NewMenu("paused", g.hud.Face,
+		scene.Button("Resume", g.scenes.Pop),
+		scene.Button("Save game", func() { g.scenes.Push(g.newSlotsMenu(true)) }),
+		scene.Button("Load game", func() { g.scenes.Push(g.newSlotsMenu(false)) }),
+		scene.Button("Settings", func() { g.scenes.Push(g.newSettingsMenu()) }),
+		scene.Button("Quit", g.exit),
+	)
+	m.Back = g.scenes.Pop
+	return &menuScene{g: g, menu: m}
+}
+
+func (g *Game) newSettingsMenu() scene.Scene {
+	v := &g.settings.View
+	c := &g.settings.Controls
+	m := scene.NewMenu("settings", g.hud.Face,
+		scene.Item{
+			Label:  func() string { return fmt.Sprintf("Sensitivity %.1f", c.Sensitivity) },
+			Change: func(d int) { c.Sensitivity = min(max(c.Sensitivity+0.1*float64(d), 0.1), 5) },
+		},
+		scene.Item{
+			Label:  func() string { return fmt.Sprintf("FOV %.0f", v.FOV) },
+			Change: func(d int) { v.FOV = min(max(v.FOV+5*float64(d), 40), 120) },
+		},
+		scene.Item{
+			Label: func() string { return "Layout " + g.layouts[g.layoutIdx].Name },
+			Change: func(d int) {
+				g.layoutIdx = (g.layoutIdx + d + len(g.layouts)) % len(g.layouts)
+				v.Layout = g.layouts[g.layoutIdx].Name
+				g.updateRenders = true
+			},
+		},
+		scene.Item{
+			Label: func() string { return fmt.Sprintf("Players %d", len(g.locals)+1) },
+			Change: func(d int) {
+				if err := g.setPlayers(len(g.locals) + 1 + d); err != nil {
+					log.Printf("could not change players: %v", err)
+				}
+			},
+		},
+		scene.Button("Keybinds", func() { g.scenes.Push(g.newKeybindsMenu()) }),
+		scene.Button("Back", g.leaveSettings),
+	)
+	m.Back = g.leaveSettings
+	return &menuScene{g: g, menu: m, background: color.RGBA{15, 20, 25, 255}}
+}
+
+// leaveSettings saves the settings and goes back
+func (g *Game) leaveSettings() {
+	if err := g.settings.Save(g.settingsFile); err != nil {
+		log.Printf("could not save settings: %v", err)
+	}
+	g.scenes.Pop()
+}
+
+func (g *Game) newKeybindsMenu() scene.Scene {
+	var items []scene.Item
+	keys := g.settings.Controls.Keys
+	for _, a := range input.ACTIONS {
+		a := a
+		items = append(items, scene.Item{
+			Label:    func() string { return fmt.Sprintf("%v  %v", strings.Join(keys[a], ", "), a) },
+			Activate: func() { g.scenes.Push(&rebindScene{g: g, action: a}) },
+		})
+	}
+	for _, k := range KEYBINDS {
+		items = append(items, scene.Label(k))
+	}
+	items = append(items, scene.Button("Back", g.scenes.Pop))
+	m := scene.NewMenu("keybinds", g.hud.Face, items...)
+	m.Back = g.scenes.Pop
+	return &menuScene{g: g, menu: m, background: color.RGBA{15, 20, 25, 255}}
+}
+
+// rebindScene waits for a key, and binds it to an action instead of its current keys
+type rebindScene struct {
+	g      *Game
+	action input.Action
+}
+
+func (r *rebindScene) Update(m *scene.Manager) error {
+	keys := inpututil.AppendJustPressedKeys(nil)
+	if len(keys) == 0 {
+		return nil
+	}
+	// escape cancels, so it can only be bound in the config file
+	if keys[0] != ebiten.KeyEscape {
+		r.g.settings.Controls.Keys[r.action] = []string{keys[0].String()}
+		r.g.rebind()
+	}
+	m.Pop()
+	return nil
+}
+
+func (r *rebindScene) Draw(screen *ebiten.Image) {
+	b := screen.Bounds()
+	vector.DrawFilledRect(screen, float32(b.Min.X), float32(b.Min.Y), float32(b.Dx()), float32(b.Dy()), color.RGBA{0, 0, 0, 200}, false)
+	text := fmt.Sprintf("press a key for %v, or escape to cancel", r.action)
+	size := hud.TextSize(r.g.hud.Face, text)
+	hud.DrawText(screen, r.g.hud.Face, text, b.Min.X+(b.Dx()-size.X)/2, b.Min.Y+(b.Dy()-size.Y)/2, color.White)
+}
+
+// exit ends the game at the next update
+func (g *Game) exit() {
+	g.quit = ebiten.Termination
+}
@@ -0,0 +1,97 @@
+// Package settings holds the user's options, saved as JSON
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/hvassaa/gaster/display"
+	"github.com/hvassaa/gaster/input"
+)
+
+type Settings struct {
+	Display  display.Options
+	Effects  Effects
+	HUD      HUD
+	Controls input.Config
+	View     View
+}
+
+type View struct {
+	// FOV is the field of view in degrees
+	FOV float64
+	// Layout is the name of the layout to start with, empty uses the default
+	Layout string
+}
+
+type HUD struct {
+	Show bool
+	// Font is a TTF or OTF file, empty uses a small built in bitmap font
+	Font     string
+	FontSize float64
+}
+
+// Effects are the post processing effects applied to the 3D view
+type Effects struct {
+	Scanlines         bool
+	ScanlineIntensity float64
+	// ScanlineSpacing is the distance in pixels between scanlines
+	ScanlineSpacing  float64
+	Vignette         bool
+	VignetteStrength float64
+	ColorGrade       bool
+	// LUT is a 256x16 PNG with 16 slices of 16x16 colors, empty uses an identity LUT
+	LUT                 string
+	ColorGradeIntensity float64
+	DamageFlash         bool
+}
+
+func Default() Settings {
+	return Settings{
+		Display: display.Options{
+			Scaling: display.SCALING_INTEGER,
+		},
+		Effects: Effects{
+			ScanlineIntensity:   0.3,
+			ScanlineSpacing:     3,
+			VignetteStrength:    0.6,
+			LUT:                 "./resources/luts/warm.png",
+			ColorGradeIntensity: 1,
+			DamageFlash:         true,
+		},
+		HUD: HUD{
+			Show:     true,
+			FontSize: 14,
+		},
+		Controls: input.Default(),
+		View: View{
+			FOV: 60,
+		},
+	}
+}
+
+// Load reads settings from path. Options missing from the file keep their
+// defaults, and a missing file gives the default settings.
+func Load(path string) (Settings, error) {
+	s := Default()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Default(), err
+	}
+	return s, nil
+}
+
+func (s Settings) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
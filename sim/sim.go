@@ -0,0 +1,216 @@
+// Package sim is the part of the game that runs every tick, without any
+// window or rendering, so it can be driven by scripts and tests.
+package sim
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hvassaa/gaster/enemy"
+	"github.com/hvassaa/gaster/input"
+	"github.com/hvassaa/gaster/lighting"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/projectile"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/weapon"
+)
+
+// PLAYER_RADIUS is how wide players are for projectiles, in blocks
+const PLAYER_RADIUS = 0.25
+
+type World struct {
+	Player *player.Player
+	// Others are players moved elsewhere, which enemies also go after
+	Others    []*player.Player
+	Map       [][]raycasting.WallType
+	BlockSize float64
+	// Noclip lets the player walk through walls
+	Noclip bool
+	// Lights flicker using Rng, or are nil
+	Lights *lighting.Lights
+	Rng    *rand.Rand
+	// Enemies are updated every tick, or are nil, and Hits is the damage they did in the last tick
+	Enemies *enemy.Group
+	Hits    []enemy.Hit
+	// Projectiles fly every tick, or are nil, and Explosions are those that ended in the last tick
+	Projectiles *projectile.Group
+	Explosions  []projectile.Explosion
+	// Shared means Enemies and Projectiles are updated by another world, and only shot at or launched in this one
+	Shared bool
+	// Arsenal is the weapons of the player, or nil, and Shots what it fired in the last tick
+	Arsenal *weapon.Arsenal
+	Shots   []weapon.Shot
+	// Prev is the frame of the tick before, to know which buttons were just pressed
+	Prev input.Frame
+	Tick int
+}
+
+func New(p *player.Player, m [][]raycasting.WallType, blockSize float64, seed int64) *World {
+	return &World{
+		Player:    p,
+		Map:       m,
+		BlockSize: blockSize,
+		Rng:       rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Seed restarts the random numbers, and forgets the previous frame, so
+// running the same frames again gives the same result
+func (w *World) Seed(seed int64) {
+	w.Rng = rand.New(rand.NewSource(seed))
+	w.Prev = input.Frame{}
+}
+
+// Update polls src and steps with its frame
+func (w *World) Update(src input.Source) input.Frame {
+	f := src.Poll()
+	w.Step(f)
+	return f
+}
+
+// Step advances the world by a tick, with the actions in f
+func (w *World) Step(f input.Frame) {
+	defer func() {
+		w.Prev = f
+		w.Tick++
+	}()
+	if w.Lights != nil {
+		w.Lights.Update(w.Rng)
+	}
+
+	if f.Look != 0 {
+		w.Player.IncreaseHozAngle(f.Look)
+	}
+	if f.Turn != 0 {
+		w.Player.IncreaseAngle(f.Turn)
+	}
+
+	// walking backwards or left is walking forward or right in the opposite direction
+	if f.Move > 0 {
+		w.walk(f.Move, 0)
+	} else if f.Move < 0 {
+		w.walk(-f.Move, raycasting.PI)
+	}
+	if f.Strafe > 0 {
+		w.walk(f.Strafe, raycasting.PI_HALF)
+	} else if f.Strafe < 0 {
+		w.walk(-f.Strafe, -raycasting.PI_HALF)
+	}
+
+	w.Shots = nil
+	if w.Arsenal != nil {
+		w.shoot(f)
+	}
+
+	w.Hits, w.Explosions = nil, nil
+	if w.Shared {
+		return
+	}
+	if w.Enemies != nil {
+		w.Hits = w.Enemies.Update(append([]*player.Player{w.Player}, w.Others...))
+		for _, h := range w.Hits {
+			if h.Projectile != "" {
+				angle := math.Atan2(h.Player.Coord.Y-h.Enemy.Coord.Y, h.Player.Coord.X-h.Enemy.Coord.X)
+				w.launch(h.Projectile, h.Enemy.Coord, angle, h.Enemy)
+			}
+		}
+	}
+	if w.Projectiles != nil {
+		w.Explosions = w.Projectiles.Update(w.targets())
+		w.explode()
+	}
+}
+
+// launch adds a projectile, if there are projectiles. Unknown types are
+// left out, as the types used are checked when they are loaded.
+func (w *World) launch(name string, c raycasting.Coordinate, angle float64, owner any) {
+	if w.Projectiles != nil {
+		w.Projectiles.Launch(name, c, angle, owner)
+	}
+}
+
+// targets are the players and living enemies, for projectiles to hit
+func (w *World) targets() []projectile.Target {
+	var res []projectile.Target
+	for _, p := range append([]*player.Player{w.Player}, w.Others...) {
+		res = append(res, projectile.Target{Coord: *p.Coord, Radius: PLAYER_RADIUS * w.BlockSize, Entity: p})
+	}
+	if w.Enemies != nil {
+		for _, e := range w.Enemies.Enemies {
+			if e.State != enemy.DEAD {
+				res = append(res, projectile.Target{Coord: e.Coord, Radius: e.Type.Radius * w.BlockSize, Entity: e})
+			}
+		}
+	}
+	return res
+}
+
+// explode does the damage of the explosions. Enemies chase the player whose projectile hurt them.
+func (w *World) explode() {
+	for _, ex := range w.Explosions {
+		from, _ := ex.Projectile.Owner.(*player.Player)
+		for _, h := range ex.Hits {
+			switch e := h.Entity.(type) {
+			case *enemy.Enemy:
+				e.Hurt(h.Damage, from)
+			case *player.Player:
+				e.Health = max(e.Health-h.Damage, 0)
+			}
+		}
+	}
+}
+
+// JustPressed is true if b is pressed in f, but was not the tick before
+func (w *World) JustPressed(f input.Frame, b input.Button) bool {
+	return f.JustPressed(w.Prev, b)
+}
+
+// shoot switches, reloads and fires the weapon in hand, and hurts the enemies hit
+func (w *World) shoot(f input.Frame) {
+	w.Arsenal.Update()
+	if w.JustPressed(f, input.NEXT_WEAPON) {
+		w.Arsenal.Next()
+	}
+	wp := w.Arsenal.Weapon()
+	if wp == nil {
+		return
+	}
+	if w.JustPressed(f, input.RELOAD) {
+		wp.Reload()
+	}
+	// automatic weapons keep firing while the trigger is held, others fire once per press
+	if !f.Pressed(input.FIRE) || !(wp.Type.Automatic || w.JustPressed(f, input.FIRE)) {
+		return
+	}
+	if wp.Type.Projectile != "" {
+		for _, a := range wp.Trigger(w.Rng, w.Player.Angle) {
+			w.launch(wp.Type.Projectile, *w.Player.Coord, a, w.Player)
+		}
+		return
+	}
+	var alive []*enemy.Enemy
+	var targets []weapon.Target
+	if w.Enemies != nil {
+		for _, e := range w.Enemies.Enemies {
+			if e.State != enemy.DEAD {
+				alive = append(alive, e)
+				targets = append(targets, weapon.Target{Coord: e.Coord, Radius: e.Type.Radius * w.BlockSize})
+			}
+		}
+	}
+	w.Shots = wp.Fire(w.Rng, *w.Player.Coord, w.Player.Angle, w.BlockSize, w.Map, targets)
+	for _, s := range w.Shots {
+		if s.Target != weapon.NO_TARGET {
+			alive[s.Target].Hurt(wp.Type.Damage, w.Player)
+		}
+	}
+}
+
+// walk moves the player at angle relative to where it looks, through walls if noclip is on
+func (w *World) walk(multiplier, angle float64) {
+	if w.Noclip {
+		w.Player.MoveWithAngle(multiplier, angle)
+	} else {
+		w.Player.Walk(multiplier, angle, w.BlockSize, w.Map)
+	}
+}
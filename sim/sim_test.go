@@ -0,0 +1,210 @@
+package sim
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hvassaa/gaster/enemy"
+	"github.com/hvassaa/gaster/input"
+	"github.com/hvassaa/gaster/maps"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/projectile"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/weapon"
+)
+
+const blockSize = maps.ROOM_BLOCK_SIZE
+
+// newWorld is the room with the player in the middle, looking east
+func newWorld() *World {
+	p := &player.Player{Coord: &raycasting.Coordinate{X: 410, Y: 410}, Speed: 5}
+	return New(p, maps.Room(), blockSize, 1)
+}
+
+func run(w *World, s *input.Script) {
+	for !s.Done() {
+		w.Update(s)
+	}
+}
+
+func TestScript(t *testing.T) {
+	t.Run("Walk forward", func(t *testing.T) {
+		w := newWorld()
+		run(w, input.NewScript(input.Walk(50)))
+		if math.Abs(w.Player.Coord.X-660) > 1e-9 || w.Player.Coord.Y != 410 {
+			t.Fatalf("Player should walk 250 east, got: %v", *w.Player.Coord)
+		}
+	})
+
+	t.Run("Turn and walk", func(t *testing.T) {
+		w := newWorld()
+		run(w, input.NewScript(input.Turn(90), input.Walk(20)))
+		if math.Abs(w.Player.Coord.X-410) > 1e-3 || math.Abs(w.Player.Coord.Y-510) > 1e-3 {
+			t.Fatalf("Player should walk 100 south after turning right, got: %v", *w.Player.Coord)
+		}
+	})
+
+	t.Run("Strafe", func(t *testing.T) {
+		w := newWorld()
+		run(w, input.NewScript(input.StrafeLeft(10)))
+		if math.Abs(w.Player.Coord.Y-360) > 1e-3 {
+			t.Fatalf("Player should strafe 50 north, got: %v", *w.Player.Coord)
+		}
+	})
+
+	t.Run("Walls stop the player", func(t *testing.T) {
+		w := newWorld()
+		run(w, input.NewScript(input.Walk(200)))
+		wall := maps.ROOM_EAST_WALL * blockSize
+		if w.Player.Coord.X >= wall-blockSize/4 || w.Player.Coord.X < wall-blockSize/2 {
+			t.Fatalf("Player should stop right before the wall at %v, got: %v", wall, *w.Player.Coord)
+		}
+	})
+
+	t.Run("Noclip walks through walls", func(t *testing.T) {
+		w := newWorld()
+		w.Noclip = true
+		run(w, input.NewScript(input.Walk(200)))
+		if w.Player.Coord.X != 1410 {
+			t.Fatalf("Player should walk through the wall, got: %v", *w.Player.Coord)
+		}
+	})
+
+	t.Run("Ticks are counted", func(t *testing.T) {
+		w := newWorld()
+		s := input.NewScript(input.Walk(3), input.Wait(4), input.Turn(10))
+		run(w, s)
+		if w.Tick != s.Ticks() || w.Tick != 8 {
+			t.Fatalf("World should have run the 8 ticks of the script, got: %v", w.Tick)
+		}
+	})
+}
+
+func TestDeterministic(t *testing.T) {
+	script := func() *input.Script {
+		return input.NewScript(input.Walk(30), input.Turn(37), input.StrafeRight(25), input.Turn(-101), input.Walk(80), input.Back(15))
+	}
+	a, b := newWorld(), newWorld()
+	run(a, script())
+	run(b, script())
+	if *a.Player.Coord != *b.Player.Coord || a.Player.Angle != b.Player.Angle {
+		t.Fatalf("Same script should give the same result, got: %v %v and %v %v", *a.Player.Coord, a.Player.Angle, *b.Player.Coord, b.Player.Angle)
+	}
+}
+
+func TestJustPressed(t *testing.T) {
+	w := newWorld()
+	use := input.Frame{Buttons: input.USE}
+	if !w.JustPressed(use, input.USE) {
+		t.Fatalf("Use should be just pressed on the first tick")
+	}
+	w.Step(use)
+	if w.JustPressed(use, input.USE) {
+		t.Fatalf("Use should not be just pressed while held")
+	}
+}
+
+func TestEnemies(t *testing.T) {
+	w := newWorld()
+	w.Player.Health = 100
+	other := &player.Player{Coord: &raycasting.Coordinate{X: 100, Y: 100}, Health: 100}
+	w.Others = []*player.Player{other}
+	w.Enemies = enemy.NewGroup(w.Map, blockSize)
+	grunt := &enemy.Type{Name: "grunt", Health: 10, Speed: 0.1, TurnSpeed: 180, SightRange: 10, FOV: 90, AttackRange: 1, AttackDamage: 5, AttackCooldown: 10}
+	// the other player is right in front of the enemy, and the player far away
+	e := enemy.New(grunt, raycasting.Coordinate{X: 120, Y: 100})
+	e.Angle = raycasting.PI
+	w.Enemies.Add(e)
+
+	hurt := 0
+	for i := 0; i < 30; i++ {
+		w.Step(input.Frame{})
+		hurt += len(w.Hits)
+	}
+	if hurt == 0 || other.Health >= 100 || w.Player.Health != 100 {
+		t.Fatalf("Enemy should attack the nearest player, got health %d and %d", w.Player.Health, other.Health)
+	}
+}
+
+func TestWeapons(t *testing.T) {
+	w := newWorld()
+	w.Player.Health = 100
+	pistol := &weapon.Type{Name: "pistol", Damage: 4, Pellets: 1, Range: 20, Cooldown: 5, Magazine: 8}
+	w.Arsenal = weapon.NewArsenal([]*weapon.Type{pistol})
+	w.Enemies = enemy.NewGroup(w.Map, blockSize)
+	grunt := &enemy.Type{Name: "grunt", Health: 10, Radius: 0.3}
+	// in front of the player, but looking away
+	e := enemy.New(grunt, raycasting.Coordinate{X: 610, Y: 410})
+	w.Enemies.Add(e)
+
+	fire := input.Frame{Buttons: input.FIRE}
+	for i := 0; i < 20; i++ {
+		w.Step(fire)
+	}
+	if e.Health != 6 || e.State != enemy.CHASE || e.Target != w.Player {
+		t.Fatalf("Holding the trigger should fire a pistol once, and the enemy should chase, got: %d health, %v", e.Health, e.State)
+	}
+	// let go of the trigger until the pistol can fire again
+	shoot := func() {
+		for i := 0; i < pistol.Cooldown; i++ {
+			w.Step(input.Frame{})
+		}
+		w.Step(fire)
+	}
+	shoot()
+	shoot()
+	if e.State != enemy.DEAD {
+		t.Fatalf("Enemy should die after enough shots, got: %d health", e.Health)
+	}
+	shoot()
+	if len(w.Shots) != 1 || !w.Shots[0].Wall {
+		t.Fatalf("Shots should go through dead enemies, got: %+v", w.Shots)
+	}
+}
+
+func TestProjectiles(t *testing.T) {
+	rocket := &projectile.Type{Name: "rocket", Speed: 0.5, Radius: 0.1, Damage: 8}
+	fireball := &projectile.Type{Name: "fireball", Speed: 0.5, Radius: 0.1, Damage: 5}
+	newProjectileWorld := func() *World {
+		w := newWorld()
+		w.Player.Health = 100
+		w.Enemies = enemy.NewGroup(w.Map, blockSize)
+		w.Projectiles = projectile.NewGroup(w.Map, blockSize, map[string]*projectile.Type{"rocket": rocket, "fireball": fireball})
+		return w
+	}
+
+	t.Run("Launchers fire projectiles", func(t *testing.T) {
+		w := newProjectileWorld()
+		launcher := &weapon.Type{Name: "launcher", Pellets: 1, Magazine: 1, Projectile: "rocket"}
+		w.Arsenal = weapon.NewArsenal([]*weapon.Type{launcher})
+		// five blocks in front of the player, looking away
+		e := enemy.New(&enemy.Type{Name: "grunt", Health: 10, Radius: 0.3}, raycasting.Coordinate{X: 610, Y: 410})
+		w.Enemies.Add(e)
+
+		w.Step(input.Frame{Buttons: input.FIRE})
+		if len(w.Projectiles.Projectiles) != 1 || e.Health != 10 {
+			t.Fatalf("Launcher should fire a rocket that has not hit yet, got: %d projectiles and %d health", len(w.Projectiles.Projectiles), e.Health)
+		}
+		for i := 0; i < 20 && w.Explosions == nil; i++ {
+			w.Step(input.Frame{})
+		}
+		if e.Health != 2 || e.Target != w.Player {
+			t.Fatalf("Rocket should hurt the enemy, which goes after the player, got: %d health", e.Health)
+		}
+	})
+
+	t.Run("Enemies fire projectiles", func(t *testing.T) {
+		w := newProjectileWorld()
+		imp := &enemy.Type{Name: "imp", Health: 10, TurnSpeed: 180, SightRange: 10, FOV: 90, AttackRange: 6, AttackCooldown: 100, Radius: 0.3, Projectile: "fireball"}
+		e := enemy.New(imp, raycasting.Coordinate{X: 610, Y: 410})
+		e.Angle = raycasting.PI
+		w.Enemies.Add(e)
+
+		for i := 0; i < 40 && w.Player.Health == 100; i++ {
+			w.Step(input.Frame{})
+		}
+		if w.Player.Health != 95 || e.Health != 10 {
+			t.Fatalf("Fireball should hurt the player, and not the enemy launching it, got: %d and %d", w.Player.Health, e.Health)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hvassaa/gaster/console"
+	"github.com/hvassaa/gaster/hud"
+	"github.com/hvassaa/gaster/input"
+	"github.com/hvassaa/gaster/input/live"
+	"github.com/hvassaa/gaster/layout"
+	"github.com/hvassaa/gaster/player"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/rendering"
+	"github.com/hvassaa/gaster/sim"
+)
+
+// MAX_PLAYERS is how many players can share the screen
+const MAX_PLAYERS = 4
+
+// local is a player sharing the screen with the first one, playing with a gamepad.
+// It has a world of its own, with the same map, but without lights, which the first world updates.
+// The enemies and projectiles are shared with the first world too, which moves them.
+type local struct {
+	world *sim.World
+	input *live.Source
+	hud   *hud.HUD
+	// frame is the last one polled, which the player is moved by
+	frame input.Frame
+}
+
+// players are the players on this screen, the first one being the one of g.world
+func (g *Game) players() []*player.Player {
+	res := []*player.Player{g.world.Player}
+	for _, l := range g.locals {
+		res = append(res, l.world.Player)
+	}
+	return res
+}
+
+// playerIndex is the index of p in players, or -1 if it is not on this screen
+func (g *Game) playerIndex(p *player.Player) int {
+	for i, pl := range g.players() {
+		if pl == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// hudOf is the HUD of player i
+func (g *Game) hudOf(i int) *hud.HUD {
+	if i == 0 {
+		return g.hud
+	}
+	return g.locals[i-1].hud
+}
+
+// currentLayout is the chosen layout, or the split screen one with more than one player
+func (g *Game) currentLayout() layout.Layout {
+	if len(g.locals) > 0 {
+		return layout.SplitScreen(len(g.locals) + 1)
+	}
+	return g.layouts[g.layoutIdx]
+}
+
+// setPlayers changes how many players share the screen. The first player uses
+// the keyboard and mouse, and the others a gamepad each. If there is a gamepad
+// for everyone, the first player gets one too.
+func (g *Game) setPlayers(n int) error {
+	if n < 1 || n > MAX_PLAYERS {
+		return fmt.Errorf("there can be 1 to %d players", MAX_PLAYERS)
+	}
+	if n > 1 && g.client != nil {
+		return errors.New("cannot share the screen while online")
+	}
+	if n > 1 && (g.playback != nil || g.recording != nil) {
+		return errors.New("cannot share the screen while recording or playing a replay")
+	}
+	gamepads := ebiten.AppendGamepadIDs(nil)
+	if len(gamepads) < n-1 {
+		return fmt.Errorf("%d players need %d gamepads, found %d", n, n-1, len(gamepads))
+	}
+
+	g.input.Gamepads = nil
+	if n > 1 {
+		g.input.Gamepads = []ebiten.GamepadID{}
+		if len(gamepads) >= n {
+			g.input.Gamepads = gamepads[:1]
+			gamepads = gamepads[1:]
+		}
+	}
+	// players that stay keep their place, and new ones join the first player
+	for len(g.locals) > n-1 {
+		g.locals = g.locals[:len(g.locals)-1]
+	}
+	for i, l := range g.locals {
+		l.input.Gamepads = gamepads[i : i+1]
+	}
+	for i := len(g.locals); i < n-1; i++ {
+		coord := *g.world.Player.Coord
+		p := &player.Player{
+			Coord:     &coord,
+			Angle:     g.world.Player.Angle,
+			Speed:     g.world.Player.Speed,
+			Health:    g.world.Player.MaxHealth,
+			MaxHealth: g.world.Player.MaxHealth,
+		}
+		src := live.New(&g.settings.Controls)
+		src.KeyboardMouse = false
+		src.Gamepads = gamepads[i : i+1]
+		w := sim.New(p, g.world.Map, BLOCK_SIZE, 1)
+		w.Shared = true
+		g.arm(w)
+		h := newHUD(g.settings.HUD, p)
+		h.Face = g.hud.Face
+		addWeaponHUD(h, w.Arsenal)
+		g.locals = append(g.locals, &local{
+			world: w,
+			input: src,
+			hud:   h,
+		})
+	}
+	g.world.Others = g.players()[1:]
+	g.updateRenders = true
+	return nil
+}
+
+// pollLocals polls the players sharing the screen. It reports whether one of them paused.
+func (g *Game) pollLocals() bool {
+	paused := false
+	for _, l := range g.locals {
+		prev := l.frame
+		l.frame = l.input.Poll()
+		paused = paused || l.frame.JustPressed(prev, input.PAUSE)
+	}
+	return paused
+}
+
+// stepLocals moves the players sharing the screen by the frames of pollLocals
+func (g *Game) stepLocals() {
+	for _, l := range g.locals {
+		l.world.Noclip = g.world.Noclip
+		// the enemies and projectiles are the ones of the first world, which is the one moving them
+		l.world.Enemies = g.world.Enemies
+		l.world.Projectiles = g.world.Projectiles
+		l.world.Step(l.frame)
+	}
+}
+
+// placeLocals puts the players sharing the screen on a new map, where the first player is
+func (g *Game) placeLocals() {
+	for _, l := range g.locals {
+		l.world.Map = g.world.Map
+		*l.world.Player.Coord = *g.world.Player.Coord
+	}
+}
+
+// rebind parses the controls again for every player
+func (g *Game) rebind() {
+	g.input.Rebind()
+	for _, l := range g.locals {
+		l.input.Rebind()
+	}
+}
+
+// spritesFor are the enemies, projectiles and the players seen by player i, on this screen and online
+func (g *Game) spritesFor(i int) []rendering.Sprite {
+	res := append(g.sprites(), g.enemySprites()...)
+	res = append(res, g.projectileSprites()...)
+	for j, p := range g.players() {
+		if j != i {
+			res = append(res, rendering.Sprite{
+				Coord: raycasting.Coordinate{X: p.Coord.X, Y: p.Coord.Y},
+				Size:  0.8,
+				Color: PLAYER_COLORS[j%len(PLAYER_COLORS)],
+			})
+		}
+	}
+	return res
+}
+
+func (g *Game) registerSplitCommands(c *console.Console) {
+	c.Register(console.Command{
+		Name:  "players",
+		Usage: "<1-4>",
+		Help:  "sets how many players share the screen, each after the first needs a gamepad",
+		Run: func(args []string) (string, error) {
+			if len(args) != 1 {
+				return "", errors.New("expected a number of players")
+			}
+			n, err := strconv.Atoi(args[0])
+			if err != nil {
+				return "", err
+			}
+			return "", g.setPlayers(n)
+		},
+	})
+}
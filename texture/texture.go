@@ -0,0 +1,102 @@
+package texture
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+const (
+	CROSS_TEXTURE = "./resources/textures/cross.csv"
+	ASD           = "./resources/textures/asd.csv"
+	SKY           = "./resources/textures/sky.png"
+)
+
+type Texture [][]uint8
+
+func Load(path string) Texture {
+	file, err := os.Open(path)
+	if err != nil {
+		panic(path + "not found")
+	}
+	defer file.Close()
+
+	textureAsStrings, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		panic(err)
+	}
+
+	rows := len(textureAsStrings)
+	cols := len(textureAsStrings[0])
+	if rows != cols {
+		panic(path + ": CSV should have same size row and column")
+	}
+
+	res := make([][]uint8, rows)
+	for i, row := range textureAsStrings {
+		r := make([]uint8, rows)
+		for j, e := range row {
+			n, err := strconv.Atoi(strings.TrimSpace(e))
+			if err != nil {
+				panic(err)
+			}
+			r[j] = uint8(n)
+		}
+		res[i] = r
+	}
+
+	return res
+}
+
+// LoadImage loads a PNG, such as a sky panorama
+func LoadImage(path string) image.Image {
+	img, err := ReadImage(path)
+	if err != nil {
+		panic(err)
+	}
+	return img
+}
+
+// ReadImage is LoadImage for files from the user, which returns an error instead of panicking
+func ReadImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return img, nil
+}
+
+// Column is the index of the texture column shown where ray hits a wall
+func (t Texture) Column(ray raycasting.Ray, blockSize float64) int {
+	var xPosOnBlock float64
+	if ray.Dir == raycasting.HORIZONTAL {
+		xPosOnBlock = math.Mod(ray.Coord.X, blockSize)
+	} else {
+		xPosOnBlock = math.Mod(ray.Coord.Y, blockSize)
+	}
+	xTextureListSize := len(t[0])
+	xTextureSliceSize := blockSize / float64(xTextureListSize)
+	xTextureIdx := min(max(int(math.Floor(xPosOnBlock/xTextureSliceSize)), 0), xTextureListSize-1)
+
+	angle := ray.Ang
+	leftWallHit := angle > raycasting.PI_HALF && angle < raycasting.PI_THREE_HALF && ray.Dir == raycasting.VERTICAL
+	bottomWallHit := angle < raycasting.PI && ray.Dir == raycasting.HORIZONTAL
+	if leftWallHit || bottomWallHit {
+		// when the way is left or down, the texture is x-mirrored
+		return xTextureListSize - xTextureIdx - 1
+	}
+	return xTextureIdx
+}
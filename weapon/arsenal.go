@@ -0,0 +1,71 @@
+package weapon
+
+// Arsenal is the weapons a player carries, with one of them in hand
+type Arsenal struct {
+	Weapons []*Weapon
+	Current int
+}
+
+// NewArsenal carries a new weapon of each type
+func NewArsenal(types []*Type) *Arsenal {
+	a := &Arsenal{}
+	for _, t := range types {
+		a.Weapons = append(a.Weapons, New(t))
+	}
+	return a
+}
+
+// Weapon is the weapon in hand, or nil if there are none
+func (a *Arsenal) Weapon() *Weapon {
+	if len(a.Weapons) == 0 {
+		return nil
+	}
+	return a.Weapons[a.Current]
+}
+
+// Next switches to the next weapon, which stops reloading the one put away
+func (a *Arsenal) Next() {
+	if len(a.Weapons) == 0 {
+		return
+	}
+	a.Weapon().reloading = 0
+	a.Current = (a.Current + 1) % len(a.Weapons)
+}
+
+// Update updates the weapon in hand
+func (a *Arsenal) Update() {
+	if w := a.Weapon(); w != nil {
+		w.Update()
+	}
+}
+
+// State is the ammo of a weapon, for saves and replays
+type State struct {
+	Name         string
+	Loaded, Ammo int
+}
+
+func (a *Arsenal) States() []State {
+	res := make([]State, len(a.Weapons))
+	for i, w := range a.Weapons {
+		res[i] = State{w.Type.Name, w.Loaded, w.Ammo}
+	}
+	return res
+}
+
+// Restore sets the ammo of the weapons named in states, and the weapon in hand.
+// Weapons are ready to fire afterwards, as if they had been put away for a while.
+func (a *Arsenal) Restore(states []State, current int) {
+	for _, w := range a.Weapons {
+		w.cooldown, w.reloading, w.SinceShot = 0, 0, New(w.Type).SinceShot
+		for _, s := range states {
+			if s.Name == w.Type.Name {
+				w.Loaded = min(max(s.Loaded, 0), w.Type.Magazine)
+				w.Ammo = min(max(s.Ammo, 0), w.Type.MaxAmmo)
+			}
+		}
+	}
+	if current >= 0 && current < len(a.Weapons) {
+		a.Current = current
+	}
+}
@@ -0,0 +1,213 @@
+// Package weapon has hitscan weapons, whose shots hit the first wall or target
+// along a ray the moment they are fired
+package weapon
+
+import (
+	"encoding/json"
+	"errors"
+	"image/color"
+	"math"
+	"math/rand"
+	"os"
+
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+// NO_TARGET is the target of shots that did not hit one
+const NO_TARGET = -1
+
+// Type is how a kind of weapon behaves. Range is in blocks and Spread in degrees.
+type Type struct {
+	Name   string
+	Damage int
+	// Pellets is how many rays each shot fires, spread randomly over Spread
+	Pellets int
+	Spread  float64
+	Range   float64
+	// Cooldown is the ticks between shots, and Automatic keeps firing while the trigger is held
+	Cooldown  int
+	Automatic bool
+	// Magazine is how many shots are loaded at once, and MaxAmmo how many more can be carried
+	Magazine    int
+	MaxAmmo     int
+	ReloadTicks int
+	// Projectile is launched for each pellet instead of casting rays, if set
+	Projectile string `json:",omitempty"`
+	// Color and Barrels are how the weapon is drawn
+	Color   color.RGBA
+	Barrels int
+}
+
+// LoadTypes reads a JSON list of types, in order
+func LoadTypes(path string) ([]*Type, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var types []*Type
+	if err := json.Unmarshal(data, &types); err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		if t.Name == "" || t.Pellets <= 0 || t.Magazine <= 0 {
+			return nil, errors.New(path + ": weapons need a name, pellets and a magazine")
+		}
+	}
+	return types, nil
+}
+
+// Target is a circle shots can hit, such as an enemy
+type Target struct {
+	Coord  raycasting.Coordinate
+	Radius float64
+}
+
+// Shot is a ray fired by a weapon, ending at what it hit
+type Shot struct {
+	Angle float64
+	End   raycasting.Coordinate
+	// Target is the index of the target hit, or NO_TARGET
+	Target int
+	// Wall is whether it hit a wall, instead of a target or nothing within range
+	Wall bool
+}
+
+type Weapon struct {
+	Type *Type
+	// Loaded is the ammo in the magazine, and Ammo the rest
+	Loaded, Ammo int
+	cooldown     int
+	reloading    int
+	// SinceShot is the ticks since the last shot, for animations
+	SinceShot int
+}
+
+// New is a weapon with a full magazine and as much ammo again
+func New(t *Type) *Weapon {
+	return &Weapon{Type: t, Loaded: t.Magazine, Ammo: min(t.Magazine, t.MaxAmmo), SinceShot: math.MaxInt32}
+}
+
+// Update counts down to the next shot, and finishes reloading
+func (w *Weapon) Update() {
+	w.cooldown = max(w.cooldown-1, 0)
+	if w.SinceShot < math.MaxInt32 {
+		w.SinceShot++
+	}
+	if w.reloading > 0 {
+		w.reloading--
+		if w.reloading == 0 {
+			n := min(w.Type.Magazine-w.Loaded, w.Ammo)
+			w.Loaded += n
+			w.Ammo -= n
+		}
+	}
+}
+
+// Reloading is how far along reloading is, from 0 to 1, or false when not reloading
+func (w *Weapon) Reloading() (float64, bool) {
+	if w.reloading == 0 {
+		return 0, false
+	}
+	return 1 - float64(w.reloading)/float64(w.Type.ReloadTicks), true
+}
+
+// Reload starts reloading, and reports whether it did
+func (w *Weapon) Reload() bool {
+	if w.reloading > 0 || w.Loaded == w.Type.Magazine || w.Ammo == 0 {
+		return false
+	}
+	w.reloading = max(w.Type.ReloadTicks, 1)
+	return true
+}
+
+// Ready is whether the weapon can fire now
+func (w *Weapon) Ready() bool {
+	return w.cooldown == 0 && w.reloading == 0 && w.Loaded > 0
+}
+
+// Give adds ammo, up to MaxAmmo
+func (w *Weapon) Give(amount int) {
+	w.Ammo = min(w.Ammo+amount, w.Type.MaxAmmo)
+}
+
+// Fired is whether the weapon fired this tick
+func (w *Weapon) Fired() bool {
+	return w.SinceShot == 0
+}
+
+// Trigger uses up a shot towards angle if the weapon is ready, and returns
+// the angle of each pellet. An empty weapon starts reloading instead.
+func (w *Weapon) Trigger(rng *rand.Rand, angle float64) []float64 {
+	if w.Loaded == 0 {
+		w.Reload()
+	}
+	if !w.Ready() {
+		return nil
+	}
+	w.Loaded--
+	w.cooldown = w.Type.Cooldown
+	w.SinceShot = 0
+	angles := make([]float64, w.Type.Pellets)
+	for i := range angles {
+		a := angle
+		if w.Type.Spread > 0 {
+			a += (rng.Float64() - 0.5) * w.Type.Spread * raycasting.DEG_TO_RAD
+		}
+		angles[i] = raycasting.NormalizeAngle(a)
+	}
+	return angles
+}
+
+// Fire shoots from c towards angle, if the weapon is ready. Each pellet
+// hits the nearest target or wall along its ray.
+func (w *Weapon) Fire(rng *rand.Rand, c raycasting.Coordinate, angle, blockSize float64, m [][]raycasting.WallType, targets []Target) []Shot {
+	angles := w.Trigger(rng, angle)
+	if angles == nil {
+		return nil
+	}
+	shots := make([]Shot, len(angles))
+	for i, a := range angles {
+		shots[i] = Cast(c, a, w.Type.Range*blockSize, blockSize, m, targets)
+	}
+	return shots
+}
+
+// Cast is a shot from c towards angle, reaching at most reach
+func Cast(c raycasting.Coordinate, angle, reach, blockSize float64, m [][]raycasting.WallType, targets []Target) Shot {
+	shot := Shot{Angle: angle, Target: NO_TARGET}
+	dist := reach
+	if ray, err := raycasting.CastRay(c, angle, blockSize, m); err == nil && ray.Dist <= reach {
+		dist = ray.Dist
+		shot.Wall = true
+	}
+	dx, dy := math.Cos(angle), math.Sin(angle)
+	for i, t := range targets {
+		if d, ok := intersect(c, dx, dy, t); ok && d < dist {
+			dist = d
+			shot.Target = i
+			shot.Wall = false
+		}
+	}
+	shot.End = raycasting.Coordinate{X: c.X + dx*dist, Y: c.Y + dy*dist}
+	return shot
+}
+
+// intersect is the distance along the ray from c in direction dx, dy to where it enters t
+func intersect(c raycasting.Coordinate, dx, dy float64, t Target) (float64, bool) {
+	// solve |c + d*dir - t| = r for the nearest d that is not behind c
+	ox, oy := c.X-t.Coord.X, c.Y-t.Coord.Y
+	b := ox*dx + oy*dy
+	disc := b*b - (ox*ox + oy*oy - t.Radius*t.Radius)
+	if disc < 0 {
+		return 0, false
+	}
+	sq := math.Sqrt(disc)
+	if d := -b - sq; d >= 0 {
+		return d, true
+	}
+	// inside the circle
+	if d := -b + sq; d >= 0 {
+		return 0, true
+	}
+	return 0, false
+}
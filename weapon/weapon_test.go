@@ -0,0 +1,155 @@
+package weapon
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/hvassaa/gaster/maps"
+	"github.com/hvassaa/gaster/raycasting"
+)
+
+const blockSize = maps.ROOM_BLOCK_SIZE
+
+var pistol = &Type{Name: "pistol", Damage: 10, Pellets: 1, Range: 20, Cooldown: 5, Magazine: 3, MaxAmmo: 6, ReloadTicks: 10}
+
+func fire(w *Weapon, targets ...Target) []Shot {
+	rng := rand.New(rand.NewSource(1))
+	return w.Fire(rng, raycasting.Coordinate{X: 100, Y: 100}, 0, blockSize, maps.Room(), targets)
+}
+
+func tick(w *Weapon, n int) {
+	for i := 0; i < n; i++ {
+		w.Update()
+	}
+}
+
+func TestCast(t *testing.T) {
+	m := maps.Room()
+	from := raycasting.Coordinate{X: 100, Y: 100}
+
+	t.Run("Walls stop shots", func(t *testing.T) {
+		s := Cast(from, 0, 1000, blockSize, m, nil)
+		if !s.Wall || s.Target != NO_TARGET || math.Abs(s.End.X-maps.ROOM_EAST_WALL*blockSize) > 1e-6 {
+			t.Fatalf("Shot should hit the east wall, got: %+v", s)
+		}
+	})
+
+	t.Run("Range limits shots", func(t *testing.T) {
+		s := Cast(from, 0, 200, blockSize, m, nil)
+		if s.Wall || math.Abs(s.End.X-300) > 1e-6 {
+			t.Fatalf("Shot should end at its range, got: %+v", s)
+		}
+	})
+
+	t.Run("Nearest target is hit", func(t *testing.T) {
+		targets := []Target{
+			{raycasting.Coordinate{X: 400, Y: 100}, 10},
+			{raycasting.Coordinate{X: 200, Y: 105}, 10},
+			{raycasting.Coordinate{X: 150, Y: 130}, 10},
+		}
+		s := Cast(from, 0, 1000, blockSize, m, targets)
+		if s.Target != 1 || s.Wall || math.Abs(s.End.X-(200-math.Sqrt(75))) > 1e-6 {
+			t.Fatalf("Shot should hit the edge of the nearest target in its way, got: %+v", s)
+		}
+	})
+
+	t.Run("Targets behind walls are safe", func(t *testing.T) {
+		targets := []Target{{raycasting.Coordinate{X: 900, Y: 100}, 10}}
+		if s := Cast(from, 0, 1000, blockSize, m, targets); s.Target != NO_TARGET {
+			t.Fatalf("Shot should not go through the wall, got: %+v", s)
+		}
+	})
+
+	t.Run("Targets behind are safe", func(t *testing.T) {
+		targets := []Target{{raycasting.Coordinate{X: 60, Y: 100}, 10}}
+		if s := Cast(from, 0, 1000, blockSize, m, targets); s.Target != NO_TARGET {
+			t.Fatalf("Shot should not hit behind the shooter, got: %+v", s)
+		}
+	})
+}
+
+func TestFire(t *testing.T) {
+	t.Run("Cooldown", func(t *testing.T) {
+		w := New(pistol)
+		if len(fire(w)) != 1 || w.Loaded != 2 {
+			t.Fatalf("Weapon should fire a shot, got: %d loaded", w.Loaded)
+		}
+		if fire(w) != nil {
+			t.Fatalf("Weapon should not fire again during the cooldown")
+		}
+		tick(w, pistol.Cooldown)
+		if fire(w) == nil {
+			t.Fatalf("Weapon should fire after the cooldown")
+		}
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		w := New(pistol)
+		for i := 0; i < pistol.Magazine; i++ {
+			fire(w)
+			tick(w, pistol.Cooldown)
+		}
+		if fire(w) != nil {
+			t.Fatalf("Empty weapon should not fire")
+		}
+		if _, ok := w.Reloading(); !ok {
+			t.Fatalf("Empty weapon should reload when fired")
+		}
+		tick(w, pistol.ReloadTicks)
+		if w.Loaded != 3 || w.Ammo != 0 {
+			t.Fatalf("Reload should move ammo into the magazine, got: %d loaded, %d ammo", w.Loaded, w.Ammo)
+		}
+		fire(w)
+		if w.Reload() {
+			t.Fatalf("Weapon should not reload without ammo")
+		}
+	})
+
+	t.Run("Spread", func(t *testing.T) {
+		shotgun := &Type{Name: "shotgun", Pellets: 8, Spread: 20, Range: 20, Magazine: 2}
+		shots := fire(New(shotgun))
+		if len(shots) != 8 {
+			t.Fatalf("Shotgun should fire all pellets, got: %d", len(shots))
+		}
+		for _, s := range shots {
+			a := s.Angle
+			if a > raycasting.PI {
+				a -= raycasting.PI_TWO
+			}
+			if math.Abs(a) > 10*raycasting.DEG_TO_RAD {
+				t.Fatalf("Pellets should stay within the spread, got: %v", a)
+			}
+		}
+		if shots[0].Angle == shots[1].Angle {
+			t.Fatalf("Pellets should spread out")
+		}
+	})
+}
+
+func TestArsenal(t *testing.T) {
+	rifle := &Type{Name: "rifle", Pellets: 1, Magazine: 30, MaxAmmo: 90}
+	a := NewArsenal([]*Type{pistol, rifle})
+	a.Weapon().Loaded = 1
+	a.Next()
+	if a.Weapon().Type != rifle {
+		t.Fatalf("Next should switch to the rifle, got: %v", a.Weapon().Type.Name)
+	}
+	states := a.States()
+
+	b := NewArsenal([]*Type{pistol, rifle})
+	b.Restore(states, 1)
+	if b.Current != 1 || b.Weapons[0].Loaded != 1 || b.Weapons[1].Ammo != 30 {
+		t.Fatalf("Restore should give the saved ammo, got: %+v", b.States())
+	}
+}
+
+func TestLoad(t *testing.T) {
+	types, err := LoadTypes("../resources/weapons/types.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) == 0 || types[0].Name != "pistol" {
+		t.Fatalf("Weapons should be loaded in order, starting with the pistol, got: %v", types)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hvassaa/gaster/enemy"
+	"github.com/hvassaa/gaster/hud"
+	"github.com/hvassaa/gaster/lighting"
+	"github.com/hvassaa/gaster/projectile"
+	"github.com/hvassaa/gaster/raycasting"
+	"github.com/hvassaa/gaster/rendering"
+	"github.com/hvassaa/gaster/sim"
+	"github.com/hvassaa/gaster/weapon"
+)
+
+const (
+	WEAPON_TYPES     = "./resources/weapons/types.json"
+	PROJECTILE_TYPES = "./resources/projectiles/types.json"
+	// MUZZLE_TICKS is how long the light of a shot or explosion lasts
+	MUZZLE_TICKS = 5
+)
+
+// muzzle is the light of a shot or explosion, fading out
+type muzzle struct {
+	light     *lighting.PointLight
+	ticks     int
+	intensity float64
+}
+
+// worlds are the worlds of the players on this screen, the first one being g.world
+func (g *Game) worlds() []*sim.World {
+	res := []*sim.World{g.world}
+	for _, l := range g.locals {
+		res = append(res, l.world)
+	}
+	return res
+}
+
+// arm gives the player of w one of every weapon
+func (g *Game) arm(w *sim.World) {
+	w.Arsenal = weapon.NewArsenal(g.weaponTypes)
+}
+
+// gunOf is the gun in the hand of player i, or nil if it has none
+func (g *Game) gunOf(i int) *rendering.Gun {
+	wp := g.worlds()[i].Arsenal.Weapon()
+	if wp == nil {
+		return nil
+	}
+	gun := &rendering.Gun{Color: wp.Type.Color, Barrels: wp.Type.Barrels, SinceShot: wp.SinceShot}
+	// the gun goes down and comes back up while reloading
+	if done, ok := wp.Reloading(); ok {
+		gun.Lowered = 1 - math.Abs(2*done-1)
+	}
+	return gun
+}
+
+// lightShots lights up around the players who fired this tick and where
+// projectiles exploded, and fades the lights of earlier shots
+func (g *Game) lightShots() {
+	kept := g.muzzles[:0]
+	for _, m := range g.muzzles {
+		if m.ticks--; m.ticks <= 0 {
+			g.world.Lights.Remove(m.light)
+			continue
+		}
+		m.light.Intensity = m.intensity * float64(m.ticks) / MUZZLE_TICKS
+		kept = append(kept, m)
+	}
+	g.muzzles = kept
+	for _, w := range g.worlds() {
+		if wp := w.Arsenal.Weapon(); wp != nil && wp.Fired() {
+			g.flashLight(*w.Player.Coord, color.RGBA{255, 210, 140, 255}, 5*BLOCK_SIZE, 2)
+		}
+	}
+	for _, ex := range g.world.Explosions {
+		radius := (ex.Projectile.Type.SplashRadius + 2) * BLOCK_SIZE
+		g.flashLight(ex.Coord, ex.Projectile.Type.Color, radius, 3)
+	}
+}
+
+// flashLight adds a light that fades out over MUZZLE_TICKS
+func (g *Game) flashLight(c raycasting.Coordinate, clr color.RGBA, radius, intensity float64) {
+	l := &lighting.PointLight{Coord: c, Color: clr, Radius: radius, Intensity: intensity}
+	g.world.Lights.Add(l)
+	g.muzzles = append(g.muzzles, &muzzle{l, MUZZLE_TICKS, intensity})
+}
+
+// projectileSprites are the projectiles flying
+func (g *Game) projectileSprites() []rendering.Sprite {
+	var res []rendering.Sprite
+	for _, p := range g.world.Projectiles.Projectiles {
+		res = append(res, rendering.Sprite{Coord: p.Coord, Size: p.Type.Size, Lift: p.Type.Lift, Color: p.Type.Color})
+	}
+	return res
+}
+
+// checkProjectiles is an error if a weapon or enemy launches a projectile of an unknown type
+func checkProjectiles(weapons []*weapon.Type, enemies map[string]*enemy.Type, projectiles map[string]*projectile.Type) error {
+	for _, t := range weapons {
+		if _, ok := projectiles[t.Projectile]; t.Projectile != "" && !ok {
+			return fmt.Errorf("weapon %v launches unknown projectile %v", t.Name, t.Projectile)
+		}
+	}
+	for _, t := range enemies {
+		if _, ok := projectiles[t.Projectile]; t.Projectile != "" && !ok {
+			return fmt.Errorf("enemy %v launches unknown projectile %v", t.Name, t.Projectile)
+		}
+	}
+	return nil
+}
+
+// addWeaponHUD shows the ammo of the weapon in hand
+func addWeaponHUD(h *hud.HUD, a *weapon.Arsenal) {
+	h.Add(&hud.Text{
+		Value: func() string {
+			wp := a.Weapon()
+			if wp == nil {
+				return ""
+			}
+			if _, ok := wp.Reloading(); ok {
+				return wp.Type.Name + " reloading"
+			}
+			return fmt.Sprintf("%s +%d", wp.Type.Name, wp.Ammo)
+		},
+		Color: color.RGBA{255, 255, 255, 220},
+	}, hud.BOTTOM_RIGHT)
+	h.Add(&hud.Slot{
+		Label: "AMMO",
+		Value: func() int {
+			if wp := a.Weapon(); wp != nil {
+				return wp.Loaded
+			}
+			return 0
+		},
+		Max: func() int {
+			if wp := a.Weapon(); wp != nil {
+				return wp.Type.Magazine
+			}
+			return 0
+		},
+		Color: color.RGBA{230, 200, 90, 255},
+	}, hud.BOTTOM_RIGHT)
+}